@@ -1,21 +1,76 @@
 package cmd
 
 import (
+	"bytes"
+	"fmt"
+
 	"github.com/spf13/cobra"
 	"github.com/vahid-haghighat/terralint/cmd/internal"
+	"github.com/vahid-haghighat/terralint/rules"
 )
 
 // applyCmd represents the apply command
 var applyCmd = &cobra.Command{
-	Use:   "apply",
+	Use:   "apply [paths...]",
 	Short: "Modifies the terraform files passed in",
-	Long:  `Modifies the terraform files passed in'`,
-	Args:  validateArgs,
+	Long: `Modifies the terraform files passed in, and the ones in the directories passed
+in. Files are only written when their content changes, keeping their
+permissions and line endings. --fix applies the fixes of other rules than the
+formatting ones too. A path of - formats the standard input to the standard
+output.`,
+	Args: validateArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return internal.Apply(terraformPath)
+		for _, id := range fixRules {
+			if _, found := rules.Lookup(id); !found {
+				return usageError(fmt.Errorf("unknown rule %q", id))
+			}
+		}
+
+		if readsStdin() {
+			content, config, err := readStdin(cmd)
+			if err != nil {
+				return err
+			}
+			formatted, err := internal.FixSource(content, stdinName, config, fixRules)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				if !bytes.Equal(content, formatted) {
+					fmt.Fprintln(cmd.OutOrStdout(), stdinName)
+				}
+				return nil
+			}
+			_, err = cmd.OutOrStdout().Write(formatted)
+			return err
+		}
+
+		targets, err := resolveTargets()
+		if err != nil {
+			return err
+		}
+		changed, applyErr := internal.Apply(targets, jobs, internal.ApplyOptions{Backup: backup, DryRun: dryRun, Fix: fixRules})
+		if dryRun {
+			for _, file := range changed {
+				if file.Target {
+					fmt.Fprintln(cmd.OutOrStdout(), file.Path)
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s (not a target, edited by the fixes of its module)\n", file.Path)
+				}
+			}
+		}
+		return applyErr
 	},
 }
 
+var backup bool
+var dryRun bool
+var fixRules []string
+
 func init() {
+	applyCmd.Flags().BoolVar(&backup, "backup", false, "Keep the original of every changed file with a "+internal.BackupSuffix+" suffix.")
+	applyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the files that would change without writing them.")
+	applyCmd.Flags().StringSliceVar(&fixRules, "fix", nil, "Also apply the fixes of these rules, like unused-declaration, before formatting.")
+
 	rootCmd.AddCommand(applyCmd)
 }
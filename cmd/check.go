@@ -1,22 +1,113 @@
 package cmd
 
 import (
+	"io"
+	"strings"
+
 	"github.com/spf13/cobra"
 	"github.com/vahid-haghighat/terralint/cmd/internal"
+	"github.com/vahid-haghighat/terralint/rules"
 )
 
+// stdinName names the standard input in diagnostics and diffs
+const stdinName = "<stdin>"
+
 // checkCmd represents the check command
 var checkCmd = &cobra.Command{
-	Use:   "check",
+	Use:   "check [paths...]",
 	Short: "Check the terraform file/files for linter rules",
-	Long: `Checks the terraform file/files for the linter rules and returns a list of
-locations where any of the builtin rules are violated.`,
+	Long: `Checks the terraform files and directories for the linter rules and returns a
+list of locations where any of the builtin rules are violated. A path of -
+checks the standard input.
+
+Exits with 1 when problems at least as severe as --fail-on are found, or more
+warnings than --max-warnings, 2 when a path doesn't exist or a file can't be
+read or parsed and 3 when the arguments, the flags or the config are invalid.`,
 	Args: validateArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return internal.Check(terraformPath)
+		reporter, err := internal.GetReporter(outputFormat)
+		if err != nil {
+			return usageError(err)
+		}
+		failOn, err := rules.ParseSeverity(failOnFlag)
+		if err != nil {
+			return usageError(err)
+		}
+		thresholds := internal.Thresholds{FailOn: failOn, MaxWarnings: maxWarnings}
+
+		if diffFlag {
+			diff, checkErr := diffTargets(cmd)
+			if _, err := io.WriteString(cmd.OutOrStdout(), diff); err != nil {
+				return err
+			}
+			if checkErr != nil {
+				return checkErr
+			}
+			if diff != "" {
+				return violationsError("found files that are not formatted")
+			}
+			return nil
+		}
+
+		// Files that failed to be checked don't hide the problems of the others
+		diagnostics, checkErr := checkTargets(cmd)
+		if err := reporter(cmd.OutOrStdout(), diagnostics); err != nil {
+			return err
+		}
+		if checkErr != nil {
+			return checkErr
+		}
+
+		if thresholds.Exceeded(diagnostics) {
+			return violationsError("found %d problems", len(diagnostics))
+		}
+		return nil
 	},
 }
 
+func checkTargets(cmd *cobra.Command) ([]rules.Diagnostic, error) {
+	if readsStdin() {
+		content, config, err := readStdin(cmd)
+		if err != nil {
+			return nil, err
+		}
+		return internal.CheckSource(content, stdinName, config)
+	}
+
+	targets, err := resolveTargets()
+	if err != nil {
+		return nil, err
+	}
+	return internal.Check(targets, jobs)
+}
+
+func diffTargets(cmd *cobra.Command) (string, error) {
+	if readsStdin() {
+		content, config, err := readStdin(cmd)
+		if err != nil {
+			return "", err
+		}
+		return internal.DiffSource(content, stdinName, config)
+	}
+
+	targets, err := resolveTargets()
+	if err != nil {
+		return "", err
+	}
+	return internal.Diff(targets, jobs)
+}
+
+var outputFormat string
+var diffFlag bool
+var failOnFlag string
+var maxWarnings int
+
 func init() {
+	checkCmd.Flags().StringVar(&outputFormat, "format", "text", "The output format, one of "+strings.Join(internal.ReportFormats(), ", ")+".")
+	checkCmd.Flags().BoolVar(&diffFlag, "diff", false, "Print the changes formatting would make as a unified diff instead of the problems.")
+	checkCmd.Flags().StringVar(&failOnFlag, "fail-on", rules.SeverityWarning.String(), "The least severe problems failing the check, one of info, warning, error.")
+	checkCmd.Flags().IntVar(&maxWarnings, "max-warnings", -1, "The number of warnings above which the check fails, even when --fail-on is error. Negative for no limit.")
+	checkCmd.MarkFlagsMutuallyExclusive("format", "diff")
+
 	rootCmd.AddCommand(checkCmd)
 }
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// The exit codes of terralint
+const (
+	ExitClean      = 0 // Nothing failed the thresholds
+	ExitViolations = 1 // Problems failed the thresholds or files aren't formatted
+	ExitFileError  = 2 // A path doesn't exist or a file couldn't be read, parsed or written
+	ExitUsageError = 3 // The arguments, the flags or the config are invalid
+)
+
+// exitError is an error ending terralint with an exit code other than the one
+// of file errors
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// usageError marks an error as caused by the way terralint was invoked,
+// unless it already has an exit code
+func usageError(err error) error {
+	var exit *exitError
+	if err == nil || errors.As(err, &exit) {
+		return err
+	}
+	return &exitError{code: ExitUsageError, err: err}
+}
+
+// inputError marks an error as caused by a path given to terralint that can't
+// be read, which exits like the files that can't be
+func inputError(err error) error {
+	return &exitError{code: ExitFileError, err: err}
+}
+
+// violationsError reports problems that fail the run
+func violationsError(format string, a ...any) error {
+	return &exitError{code: ExitViolations, err: fmt.Errorf(format, a...)}
+}
+
+// exitCode returns the exit code for the error a command returned. Errors not
+// marked otherwise come from the files.
+func exitCode(err error) int {
+	if err == nil {
+		return ExitClean
+	}
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+	return ExitFileError
+}
+
+// Execute runs the command of the arguments and exits with its exit code
+func Execute() {
+	cmd, err := rootCmd.ExecuteC()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		if exitCode(err) == ExitUsageError {
+			fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+		}
+	}
+	os.Exit(exitCode(err))
+}
+
+func init() {
+	// Errors are printed once by Execute, and the usage only follows the errors
+	// of the invocation
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError(err)
+	})
+}
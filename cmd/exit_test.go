@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestExitCode(t *testing.T) {
+	dir := t.TempDir()
+	formatted := filepath.Join(dir, "formatted.tf")
+	if err := os.WriteFile(formatted, []byte("output \"a\" {\n  description = \"A\"\n  value       = 1\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unformatted := filepath.Join(dir, "unformatted", "main.tf")
+	if err := os.MkdirAll(filepath.Dir(unformatted), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(unformatted, []byte("locals {\n  a=1\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"Clean", []string{"check", formatted}, ExitClean},
+		{"Violations", []string{"check", "--diff", unformatted}, ExitViolations},
+		{"Missing path", []string{"check", filepath.Join(dir, "missing.tf")}, ExitFileError},
+		{"Unknown flag", []string{"check", "--unknown", formatted}, ExitUsageError},
+		{"Unknown format", []string{"check", "--format", "yaml", formatted}, ExitUsageError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rootCmd.SetArgs(test.args)
+			rootCmd.SetOut(io.Discard)
+			defer func() {
+				rootCmd.SetOut(nil)
+				resetCheckFlags()
+			}()
+
+			_, err := rootCmd.ExecuteC()
+			if actual := exitCode(err); actual != test.expected {
+				t.Errorf("Expected exit code %d, got %d for error: %v", test.expected, actual, err)
+			}
+		})
+	}
+}
+
+// resetCheckFlags puts the flags of the check command back to their defaults,
+// since they keep their values and whether they were set between runs
+func resetCheckFlags() {
+	outputFormat, diffFlag = "text", false
+	checkCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		flag.Changed = false
+	})
+}
+
+func TestUsageErrorKeepsExitCode(t *testing.T) {
+	err := usageError(inputError(errors.New("missing")))
+	if actual := exitCode(err); actual != ExitFileError {
+		t.Errorf("Expected exit code %d, got %d", ExitFileError, actual)
+	}
+	if actual := exitCode(usageError(errors.New("bad flag"))); actual != ExitUsageError {
+		t.Errorf("Expected exit code %d, got %d", ExitUsageError, actual)
+	}
+}
@@ -1,52 +1,147 @@
 package internal
 
 import (
+	"bytes"
 	"io/fs"
 	"os"
 	"path/filepath"
 )
 
-func Apply(filePath string) error {
-	fileInfo, err := os.Stat(filePath)
+// BackupSuffix is appended to the path of a file to name its backup
+const BackupSuffix = ".bak"
 
+// ApplyOptions change how Apply writes the files it formats
+type ApplyOptions struct {
+	Backup bool     // Whether to keep the original of every changed file next to it
+	DryRun bool     // Whether to leave the files as they are and only report the changes
+	Fix    []string // The IDs of the rules whose fixes are applied before formatting
+}
+
+// ChangedFile is a file Apply changed, or would change in a dry run
+type ChangedFile struct {
+	Path   string // The path relative to the working directory
+	Target bool   // Whether it's a target, rather than another file of their module a fix edits
+}
+
+// Apply formats the target files, and the included files of the target
+// directories, in place and returns the files that changed. Files that are
+// already formatted are not written. The other files of their modules that the
+// fixes edit, like the ones referring to a renamed resource, are written too
+// without being formatted, unless the config excludes them or an ignore file
+// skips them, which leaves the fixes editing them out.
+func Apply(targets []Target, jobs int, options ApplyOptions) ([]ChangedFile, error) {
+	var plan *fixPlan
+	if len(options.Fix) > 0 {
+		// Only the fixes found in the target files are applied, which takes
+		// knowing all of them before fixing any
+		files, err := processTrees(targets, jobs, func(path string, config *Config) (*Config, error) {
+			return config, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if plan, err = newFixPlan(files, options.Fix); err != nil {
+			return nil, err
+		}
+	}
+
+	results, err := processTrees(targets, jobs, func(path string, config *Config) (bool, error) {
+		return applyRulesToFile(path, config, options, plan)
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var others []fileResult[bool]
+	if plan != nil {
+		others = plan.applyOthers(options)
 	}
 
-	if fileInfo.IsDir() {
-		return applyRulesToDirectory(filePath)
+	var changed []ChangedFile
+	for _, result := range results {
+		if result.value {
+			changed = append(changed, ChangedFile{Path: displayPath(result.path), Target: true})
+		}
+	}
+	for _, result := range others {
+		if result.value {
+			changed = append(changed, ChangedFile{Path: displayPath(result.path)})
+		}
 	}
-	return applyRulesToFile(filePath)
+	return changed, resultErrors(append(results, others...))
 }
 
-func applyRulesToFile(filePath string) error {
-	extension := filepath.Ext(filePath)
-
-	if extension != ".tf" && extension != ".tfvars" {
-		return nil
+func applyRulesToFile(filePath string, config *Config, options ApplyOptions, plan *fixPlan) (bool, error) {
+	// Writing through a symbolic link keeps the link
+	filePath, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		return false, err
 	}
-	formattedBytes, err := getFormattedContent(filePath)
-
+	info, err := os.Stat(filePath)
 	if err != nil {
-		return err
+		return false, err
+	}
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, err
 	}
 
-	return os.WriteFile(filePath, formattedBytes, 0666)
+	fixed := original
+	if plan != nil {
+		if fixed, err = plan.apply(filePath, original, config); err != nil {
+			return false, err
+		}
+	}
+	formattedBytes, err := FormatSource(fixed, filePath, config)
+	if err != nil {
+		return false, err
+	}
+	return writeChanged(filePath, info.Mode().Perm(), original, formattedBytes, options)
 }
 
-func applyRulesToDirectory(directoryPath string) error {
-	err := filepath.WalkDir(directoryPath, func(path string, d fs.DirEntry, err error) error {
-		if path == directoryPath {
-			return nil
-		}
-		stat, _ := os.Stat(path)
-		if stat.IsDir() {
-			return applyRulesToDirectory(path)
+// writeChanged writes the updated content of a file when it differs from the
+// original, and reports whether it does
+func writeChanged(filePath string, perm fs.FileMode, original, updated []byte, options ApplyOptions) (bool, error) {
+	if bytes.Equal(original, updated) {
+		return false, nil
+	}
+	if options.DryRun {
+		return true, nil
+	}
+
+	if options.Backup {
+		if err := writeFile(filePath+BackupSuffix, original, perm); err != nil {
+			return false, err
 		}
-		return applyRulesToFile(path)
-	})
+	}
+	return true, writeFile(filePath, updated, perm)
+}
+
+// writeFile replaces the file at path with a temporary file of the same
+// directory, so that an interruption leaves either the old or the new content
+// and never a part of it
+func writeFile(path string, content []byte, perm fs.FileMode) (err error) {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	return nil
+	defer func() {
+		if err != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	if _, err = temp.Write(content); err != nil {
+		return err
+	}
+	if err = temp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = temp.Sync(); err != nil {
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
 }
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	files := map[string]string{
+		"formatted.tf": "resource \"a\" \"b\" {\n  x = 1\n}\n",
+		"spaced.tf":    "resource \"a\" \"b\" {\n      x   = 1\n}\n",
+		"windows.tf":   "resource \"a\" \"b\" {\r\n x=1 # note\r\n}\r\n",
+	}
+	expected := map[string]string{
+		"formatted.tf": files["formatted.tf"],
+		"spaced.tf":    files["formatted.tf"],
+		"windows.tf":   "resource \"a\" \"b\" {\r\n  x = 1 # note\r\n}\r\n",
+	}
+
+	write := func(t *testing.T) string {
+		t.Helper()
+		root := t.TempDir()
+		writeFiles(t, root, files)
+		// Apply keeps the permissions the files had
+		for name := range files {
+			if err := os.Chmod(filepath.Join(root, name), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return root
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		root := write(t)
+		changed, err := Apply([]Target{{Path: root, Config: DefaultConfig(root)}}, 2, ApplyOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("Failed to apply: %v", err)
+		}
+		if names := baseNames(changed); !reflect.DeepEqual(names, []string{"spaced.tf", "windows.tf"}) {
+			t.Errorf("Expected the unformatted files to be reported, got %v", names)
+		}
+		for name, content := range files {
+			if actual, _ := os.ReadFile(filepath.Join(root, name)); string(actual) != content {
+				t.Errorf("%s was written during a dry run", name)
+			}
+		}
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		root := write(t)
+		if _, err := Apply([]Target{{Path: root, Config: DefaultConfig(root)}}, 2, ApplyOptions{Backup: true}); err != nil {
+			t.Fatalf("Failed to apply: %v", err)
+		}
+
+		for name, content := range expected {
+			path := filepath.Join(root, name)
+			if actual, _ := os.ReadFile(path); string(actual) != content {
+				t.Errorf("%s mismatch: expected %q, got %q", name, content, actual)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("%s lost its permissions: %v %v", name, info.Mode(), err)
+			}
+		}
+		if backup, _ := os.ReadFile(filepath.Join(root, "spaced.tf"+BackupSuffix)); string(backup) != files["spaced.tf"] {
+			t.Errorf("Backup mismatch: got %q", backup)
+		}
+		if _, err := os.Stat(filepath.Join(root, "formatted.tf"+BackupSuffix)); !os.IsNotExist(err) {
+			t.Errorf("Expected no backup of an unchanged file")
+		}
+
+		entries, _ := os.ReadDir(root)
+		if len(entries) != 5 {
+			t.Errorf("Expected the files and two backups, got %d entries", len(entries))
+		}
+	})
+}
+
+func baseNames(files []ChangedFile) []string {
+	var names []string
+	for _, file := range files {
+		names = append(names, filepath.Base(file.Path))
+	}
+	return names
+}
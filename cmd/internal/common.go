@@ -2,6 +2,7 @@ package internal
 
 import (
 	"math"
+	"path/filepath"
 )
 
 type PrioritySetting struct {
@@ -78,12 +79,23 @@ var priorities = map[string]*PriorityLists{
 	"terraform": {},
 	"locals":    {},
 	"":          {},
+	// The variables set by .tfvars files keep the order they are written in
+	"tfvars": {},
 }
 
 const sectionLabel = "terralint"
 
 var rootInternalSectionName = []string{"root"}
 
+// rootSection returns the key of the priority lists of the top level of a
+// file, which is "tfvars" for the .tfvars files that only set variables
+func rootSection(filePath string) string {
+	if filepath.Ext(filePath) == ".tfvars" {
+		return "tfvars"
+	}
+	return rootInternalSectionName[0]
+}
+
 func getPriorities(key string) *PriorityLists {
 	if _, found := priorities[key]; found {
 		return priorities[key]
@@ -0,0 +1,412 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/vahid-haghighat/terralint/rules"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ConfigFileName is the name of the configuration file looked up from the target
+const ConfigFileName = ".terralint.hcl"
+
+var defaultInclude = []string{"*.tf", "*.tfvars"}
+
+// Config is the project configuration read from a .terralint.hcl file
+type Config struct {
+	Dir        string                    // The directory the globs are relative to
+	Include    []string                  // Globs of the files to lint
+	Exclude    []string                  // Globs of the files to skip even if included
+	Rules      map[string]RuleConfig     // Rule settings by rule ID
+	Priorities map[string]*PriorityLists // Priority lists by block type, replacing the defaults
+
+	RespectGitignore bool // Whether the .gitignore files are honored like .terralintignore files
+}
+
+// RuleConfig holds the settings of a single rule
+type RuleConfig struct {
+	Enabled    bool
+	Severity   *rules.Severity // Nil keeps the default severity of the rule
+	Convention string          // The naming convention of the naming rules, empty for the default
+}
+
+// DefaultConfig returns the configuration used when there is no config file,
+// with globs relative to dir
+func DefaultConfig(dir string) *Config {
+	return &Config{
+		Dir:        dir,
+		Include:    defaultInclude,
+		Rules:      make(map[string]RuleConfig),
+		Priorities: make(map[string]*PriorityLists),
+	}
+}
+
+// ResolveConfig loads the config file at configPath, or when it's empty the
+// first .terralint.hcl found walking up from target. Without a config file
+// the defaults apply.
+func ResolveConfig(target, configPath string) (*Config, error) {
+	if configPath != "" {
+		return LoadConfig(configPath)
+	}
+
+	dir := target
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		dir = filepath.Dir(target)
+	}
+
+	found, err := FindConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	if found == "" {
+		return DefaultConfig(dir), nil
+	}
+	return LoadConfig(found)
+}
+
+// FindConfig returns the path of the first .terralint.hcl file in dir or one
+// of its parents, or an empty string when there is none
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		path := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadConfig reads and validates the config file at path
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(content, path, dir)
+}
+
+var configSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "include"},
+		{Name: "exclude"},
+		{Name: "respect_gitignore"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "rule", LabelNames: []string{"id"}},
+		{Type: "priorities", LabelNames: []string{"block_type"}},
+	},
+}
+
+var ruleSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "enabled"},
+		{Name: "severity"},
+		{Name: "convention"},
+	},
+}
+
+var prioritiesSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "prepended_attributes"},
+		{Type: "appended_attributes"},
+		{Type: "prepended_blocks"},
+	},
+}
+
+var prioritySettingSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "names", Required: true},
+		{Name: "new_lines_after"},
+		{Name: "new_lines_before"},
+	},
+}
+
+func parseConfig(content []byte, filename, dir string) (*Config, error) {
+	file, diags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	body, diags := file.Body.Content(configSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	var err error
+	config := DefaultConfig(dir)
+	if attr, found := body.Attributes["include"]; found {
+		if config.Include, err = stringList(attr); err != nil {
+			return nil, err
+		}
+	}
+	if attr, found := body.Attributes["exclude"]; found {
+		if config.Exclude, err = stringList(attr); err != nil {
+			return nil, err
+		}
+	}
+
+	if attr, found := body.Attributes["respect_gitignore"]; found {
+		value, err := attributeValue(attr, cty.Bool)
+		if err != nil {
+			return nil, err
+		}
+		config.RespectGitignore = value.True()
+	}
+
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "rule":
+			id := block.Labels[0]
+			rule, found := rules.Lookup(id)
+			if !found {
+				return nil, fmt.Errorf("%s: unknown rule %q", block.LabelRanges[0], id)
+			}
+			if _, found := config.Rules[id]; found {
+				return nil, fmt.Errorf("%s: rule %q is configured twice", block.LabelRanges[0], id)
+			}
+			ruleConfig, err := parseRuleConfig(block, rule)
+			if err != nil {
+				return nil, err
+			}
+			config.Rules[id] = ruleConfig
+		case "priorities":
+			blockType := block.Labels[0]
+			if _, found := config.Priorities[blockType]; found {
+				return nil, fmt.Errorf("%s: priorities of %q are configured twice", block.LabelRanges[0], blockType)
+			}
+			lists, err := parsePriorityLists(block)
+			if err != nil {
+				return nil, err
+			}
+			config.Priorities[blockType] = lists
+		}
+	}
+
+	return config, nil
+}
+
+func parseRuleConfig(block *hcl.Block, rule rules.Rule) (RuleConfig, error) {
+	ruleConfig := RuleConfig{Enabled: true}
+
+	body, diags := block.Body.Content(ruleSchema)
+	if diags.HasErrors() {
+		return ruleConfig, diags
+	}
+
+	if attr, found := body.Attributes["enabled"]; found {
+		value, err := attributeValue(attr, cty.Bool)
+		if err != nil {
+			return ruleConfig, err
+		}
+		ruleConfig.Enabled = value.True()
+	}
+
+	if attr, found := body.Attributes["severity"]; found {
+		value, err := attributeValue(attr, cty.String)
+		if err != nil {
+			return ruleConfig, err
+		}
+		severity, err := rules.ParseSeverity(value.AsString())
+		if err != nil {
+			return ruleConfig, fmt.Errorf("%s: %w", attr.Expr.Range(), err)
+		}
+		ruleConfig.Severity = &severity
+	}
+
+	if attr, found := body.Attributes["convention"]; found {
+		switch rule.(type) {
+		case labelNamingRule, attributeNamingRule:
+		default:
+			return ruleConfig, fmt.Errorf("%s: rule %q has no naming convention", attr.NameRange, rule.ID())
+		}
+		value, err := attributeValue(attr, cty.String)
+		if err != nil {
+			return ruleConfig, err
+		}
+		if _, found := namingConventions[value.AsString()]; !found {
+			return ruleConfig, fmt.Errorf("%s: unknown naming convention %q", attr.Expr.Range(), value.AsString())
+		}
+		ruleConfig.Convention = value.AsString()
+	}
+
+	return ruleConfig, nil
+}
+
+func parsePriorityLists(block *hcl.Block) (*PriorityLists, error) {
+	body, diags := block.Body.Content(prioritiesSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	lists := &PriorityLists{}
+	for _, nested := range body.Blocks {
+		setting, err := parsePrioritySetting(nested)
+		if err != nil {
+			return nil, err
+		}
+
+		switch nested.Type {
+		case "prepended_attributes":
+			lists.PrependedAttributes = append(lists.PrependedAttributes, setting)
+		case "appended_attributes":
+			lists.AppendedAttributes = append(lists.AppendedAttributes, setting)
+		case "prepended_blocks":
+			lists.PrependedBlocks = append(lists.PrependedBlocks, setting)
+		}
+	}
+	return lists, nil
+}
+
+func parsePrioritySetting(block *hcl.Block) (PrioritySetting, error) {
+	var setting PrioritySetting
+
+	body, diags := block.Body.Content(prioritySettingSchema)
+	if diags.HasErrors() {
+		return setting, diags
+	}
+
+	names, err := stringList(body.Attributes["names"])
+	if err != nil {
+		return setting, err
+	}
+	setting.Names = names
+
+	if attr, found := body.Attributes["new_lines_after"]; found {
+		if setting.NewLineCountAfter, err = lineCount(attr); err != nil {
+			return setting, err
+		}
+	}
+	if attr, found := body.Attributes["new_lines_before"]; found {
+		if setting.NewlineCountBefore, err = lineCount(attr); err != nil {
+			return setting, err
+		}
+	}
+	return setting, nil
+}
+
+// attributeValue evaluates a constant attribute and converts it to the type
+func attributeValue(attr *hcl.Attribute, want cty.Type) (cty.Value, error) {
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	if value.IsNull() || !value.IsKnown() || !value.Type().Equals(want) {
+		return cty.NilVal, fmt.Errorf("%s: %s must be a %s", attr.Expr.Range(), attr.Name, want.FriendlyName())
+	}
+	return value, nil
+}
+
+func stringList(attr *hcl.Attribute) ([]string, error) {
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	invalid := fmt.Errorf("%s: %s must be a list of strings", attr.Expr.Range(), attr.Name)
+	if value.IsNull() || !value.IsKnown() || !(value.Type().IsTupleType() || value.Type().IsListType()) {
+		return nil, invalid
+	}
+
+	list := make([]string, 0, value.LengthInt())
+	for it := value.ElementIterator(); it.Next(); {
+		_, element := it.Element()
+		if element.IsNull() || !element.Type().Equals(cty.String) {
+			return nil, invalid
+		}
+		list = append(list, element.AsString())
+	}
+	return list, nil
+}
+
+func lineCount(attr *hcl.Attribute) (int, error) {
+	value, err := attributeValue(attr, cty.Number)
+	if err != nil {
+		return 0, err
+	}
+
+	count, accuracy := value.AsBigFloat().Int64()
+	if accuracy != 0 || count < 0 {
+		return 0, fmt.Errorf("%s: %s must be a whole number that isn't negative", attr.Expr.Range(), attr.Name)
+	}
+	return int(count), nil
+}
+
+// priorities returns the priority lists of a block type, "root" being the top
+// level of .tf files and "tfvars" the one of .tfvars files
+func (c *Config) priorities(blockType string) *PriorityLists {
+	if c != nil {
+		if lists, found := c.Priorities[blockType]; found {
+			return lists
+		}
+	}
+	return getPriorities(blockType)
+}
+
+// Includes reports whether the file at path is linted
+func (c *Config) Includes(path string) bool {
+	name := c.relativePath(path)
+	return matchAny(c.Include, name) && !matchAny(c.Exclude, name)
+}
+
+// relativePath returns the slash separated path of a file relative to the
+// config directory, or its absolute path when it's outside of it
+func (c *Config) relativePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	if rel, err := filepath.Rel(c.Dir, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(abs)
+}
+
+// enabledRules returns the registered rules the config leaves enabled, with
+// the configured severities and priority lists applied
+func (c *Config) enabledRules() []rules.Rule {
+	var enabled []rules.Rule
+	for _, rule := range rules.All() {
+		settings, found := c.Rules[rule.ID()]
+		if found && !settings.Enabled {
+			continue
+		}
+		if configurable, ok := rule.(configurableRule); ok {
+			rule = configurable.withConfig(c)
+		}
+		if found && settings.Severity != nil {
+			rule = rules.WithSeverity(rule, *settings.Severity)
+		}
+		enabled = append(enabled, rule)
+	}
+	return enabled
+}
+
+// configurableRule is a built-in rule that depends on the config
+type configurableRule interface {
+	withConfig(config *Config) rules.Rule
+}
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/vahid-haghighat/terralint/rules"
+)
+
+func TestParseConfig(t *testing.T) {
+	content := []byte(`
+include = ["*.tf"]
+exclude = ["examples/**"]
+
+rule "order" {
+  enabled = false
+}
+
+rule "format" {
+  severity = "info"
+}
+
+rule "label-naming" {
+  convention = "kebab-case"
+}
+
+priorities "resource" {
+  prepended_attributes {
+    names           = ["for_each", "count"]
+    new_lines_after = 1
+  }
+  appended_attributes {
+    names            = ["tags"]
+    new_lines_before = 1
+  }
+}
+`)
+
+	config, err := parseConfig(content, ConfigFileName, "/project")
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if expected := []string{"*.tf"}; !reflect.DeepEqual(expected, config.Include) {
+		t.Errorf("Include mismatch: expected %v, got %v", expected, config.Include)
+	}
+	if expected := []string{"examples/**"}; !reflect.DeepEqual(expected, config.Exclude) {
+		t.Errorf("Exclude mismatch: expected %v, got %v", expected, config.Exclude)
+	}
+
+	if config.Rules["order"].Enabled {
+		t.Errorf("Rule order should be disabled")
+	}
+	format := config.Rules["format"]
+	if !format.Enabled || format.Severity == nil || *format.Severity != rules.SeverityInfo {
+		t.Errorf("Rule format should be enabled with an info severity, got %+v", format)
+	}
+
+	if convention := config.Rules["label-naming"].Convention; convention != "kebab-case" {
+		t.Errorf("Rule label-naming should have the kebab-case convention, got %q", convention)
+	}
+
+	expected := &PriorityLists{
+		PrependedAttributes: []PrioritySetting{{Names: []string{"for_each", "count"}, NewLineCountAfter: 1}},
+		AppendedAttributes:  []PrioritySetting{{Names: []string{"tags"}, NewlineCountBefore: 1}},
+	}
+	if actual := config.priorities("resource"); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Priorities mismatch: expected %+v, got %+v", expected, actual)
+	}
+	if actual := config.priorities("module"); actual != getPriorities("module") {
+		t.Errorf("Block types without priorities should keep the defaults")
+	}
+
+	var enabled []string
+	for _, rule := range config.enabledRules() {
+		enabled = append(enabled, rule.ID())
+		if rule.ID() == "format" && rule.Severity() != rules.SeverityInfo {
+			t.Errorf("Configured severity was not applied to rule format")
+		}
+	}
+	if expected := []string{
+		"attribute-naming", "format", "label-naming", "output-description", "output-sensitive", "redundant-resource-name",
+		"undefined-reference", "unused-declaration", "variable-default", "variable-description", "variable-type",
+	}; !reflect.DeepEqual(expected, enabled) {
+		t.Errorf("Enabled rules mismatch: expected %v, got %v", expected, enabled)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"Unknown rule", `rule "missing" {}`},
+		{"Rule configured twice", "rule \"format\" {}\nrule \"format\" {}"},
+		{"Unknown severity", `rule "format" { severity = "fatal" }`},
+		{"Enabled is not a bool", `rule "format" { enabled = "no" }`},
+		{"Include is not a list", `include = "*.tf"`},
+		{"Unknown attribute", `extensions = [".tf"]`},
+		{"Priority names are missing", `priorities "resource" { prepended_attributes {} }`},
+		{"Unknown naming convention", `rule "label-naming" { convention = "SCREAMING_CASE" }`},
+		{"Convention of a rule without names", `rule "format" { convention = "snake_case" }`},
+		{"Negative line count", `priorities "resource" { prepended_attributes { names = ["count"], new_lines_after = -1 } }`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := parseConfig([]byte(test.content), ConfigFileName, "/project"); err == nil {
+				t.Errorf("Expected an error for %q", test.content)
+			}
+		})
+	}
+}
+
+func TestResolveConfig(t *testing.T) {
+	root := t.TempDir()
+	module := filepath.Join(root, "modules", "network")
+	if err := os.MkdirAll(module, 0755); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(root, ConfigFileName)
+	if err := os.WriteFile(configFile, []byte(`exclude = ["modules/**/generated.tf"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := FindConfig(module)
+	if err != nil {
+		t.Fatalf("Failed to find config: %v", err)
+	}
+	if found != configFile {
+		t.Errorf("Expected config %s, got %s", configFile, found)
+	}
+
+	config, err := ResolveConfig(module, "")
+	if err != nil {
+		t.Fatalf("Failed to resolve config: %v", err)
+	}
+	if !config.Includes(filepath.Join(module, "main.tf")) {
+		t.Errorf("main.tf should be included")
+	}
+	if config.Includes(filepath.Join(module, "generated.tf")) {
+		t.Errorf("generated.tf should be excluded by the config found in %s", root)
+	}
+
+	explicit := filepath.Join(t.TempDir(), "custom.hcl")
+	if err := os.WriteFile(explicit, []byte(`include = ["*.tfvars"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err = ResolveConfig(module, explicit)
+	if err != nil {
+		t.Fatalf("Failed to resolve config: %v", err)
+	}
+	if config.Includes(filepath.Join(module, "main.tf")) {
+		t.Errorf("The --config file should replace the discovered one")
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		matches bool
+	}{
+		{"*.tf", "main.tf", true},
+		{"*.tf", "modules/network/main.tf", true},
+		{"*.tf", "terraform.tfvars", false},
+		{"modules/*.tf", "modules/main.tf", true},
+		{"modules/*.tf", "modules/network/main.tf", false},
+		{"**/generated/*.tf", "generated/main.tf", true},
+		{"**/generated/*.tf", "a/b/generated/main.tf", true},
+		{"examples/**", "examples/basic/main.tf", true},
+		{"examples/**", "modules/examples/main.tf", false},
+		{"./examples/**", "examples/main.tf", true},
+	}
+
+	for _, test := range tests {
+		if actual := matchGlob(test.pattern, test.name); actual != test.matches {
+			t.Errorf("matchGlob(%q, %q): expected %v, got %v", test.pattern, test.name, test.matches, actual)
+		}
+	}
+}
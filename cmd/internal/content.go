@@ -1,18 +1,66 @@
 package internal
 
 import (
+	"bytes"
+
 	"github.com/vahid-haghighat/terralint/parser"
-	"os"
+	"github.com/vahid-haghighat/terralint/parser/types"
+	"github.com/vahid-haghighat/terralint/printer"
 )
 
-func getFormattedContent(filePath string) ([]byte, error) {
-	root, err := parser.ParseTerraformFile(filePath)
-	root = root
+// FormatSource parses the in-memory content of a file, which can also come from
+// the standard input, and returns it formatted with the line endings it had
+func FormatSource(content []byte, filePath string, config *Config) ([]byte, error) {
+	return formatSource(content, filePath, config, true)
+}
+
+// formatSource formats content, moving the items that are out of order only
+// when move is set
+func formatSource(content []byte, filePath string, config *Config, move bool) ([]byte, error) {
+	// Heredocs and comments keep their \r otherwise, which would be doubled
+	windows := crlf(content)
+	if windows {
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	}
+
+	root, err := parser.ParseSource(content, filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	// For now, just return the original file content
-	// In the future, we can implement formatting based on the parsed AST
-	return os.ReadFile(filePath)
+	formatted := formatRoot(root, filePath, content, config, move)
+	if windows {
+		formatted = bytes.ReplaceAll(formatted, []byte("\n"), []byte("\r\n"))
+	}
+	return formatted, nil
+}
+
+// FixSource applies the fixes of the rules with the given IDs to content that
+// doesn't have to come from a file, and formats the result. The rules that
+// need the rest of the module are skipped.
+func FixSource(content []byte, filePath string, config *Config, fix []string) ([]byte, error) {
+	if len(fix) > 0 {
+		fixed, err := fixFile(content, filePath, config, fix)
+		if err != nil {
+			return nil, err
+		}
+		content = fixed
+	}
+	return FormatSource(content, filePath, config)
+}
+
+// crlf reports whether the lines of content end with \r\n, which is decided by
+// the first one
+func crlf(content []byte) bool {
+	i := bytes.IndexByte(content, '\n')
+	return i > 0 && content[i-1] == '\r'
+}
+
+// formatRoot prints root with the spacing the priority lists of the config
+// require, reordering it first when move is set. The regions formatting is
+// turned off for are copied from source.
+func formatRoot(root *types.Root, filePath string, source []byte, config *Config, move bool) []byte {
+	order := reorderRoot(root, filePath, config, move)
+	printerConfig := &printer.Config{BlankLines: order.blankLines, Source: source}
+	return printerConfig.Print(root)
 }
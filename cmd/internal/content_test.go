@@ -0,0 +1,24 @@
+package internal
+
+import "testing"
+
+func TestFormatSourceKeepsCRLF(t *testing.T) {
+	content := "/* a\r\n   block */\r\nlocals {\r\n  a=<<EOT\r\none\r\ntwo\r\nEOT\r\n}\r\n"
+	expected := "/* a\r\n   block */\r\nlocals {\r\n  a = <<EOT\r\none\r\ntwo\r\nEOT\r\n}\r\n"
+
+	formatted, err := FormatSource([]byte(content), "main.tf", DefaultConfig("."))
+	if err != nil {
+		t.Fatalf("Failed to format: %v", err)
+	}
+	if string(formatted) != expected {
+		t.Errorf("Formatted output mismatch:\nexpected: %q\ngot:      %q", expected, formatted)
+	}
+
+	again, err := FormatSource(formatted, "main.tf", DefaultConfig("."))
+	if err != nil {
+		t.Fatalf("Failed to format the formatted output: %v", err)
+	}
+	if string(again) != expected {
+		t.Errorf("Formatting is not stable:\nexpected: %q\ngot:      %q", expected, again)
+	}
+}
@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines around the changes of a hunk
+const diffContext = 3
+
+// Diff returns the unified diff formatting would apply to the target files,
+// and to the included files of the target directories, with the paths of the
+// files relative to the working directory
+func Diff(targets []Target, jobs int) (string, error) {
+	results, err := processTrees(targets, jobs, diffFile)
+	if err != nil {
+		return "", err
+	}
+
+	var diffs strings.Builder
+	for _, result := range results {
+		diffs.WriteString(result.value)
+	}
+	return diffs.String(), resultErrors(results)
+}
+
+func diffFile(filePath string, config *Config) (string, error) {
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	return DiffSource(original, displayPath(filePath), config)
+}
+
+// DiffSource returns the unified diff formatting would apply to content that
+// doesn't have to come from a file, using name in the file headers
+func DiffSource(original []byte, name string, config *Config) (string, error) {
+	formatted, err := FormatSource(original, name, config)
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff(name, string(original), string(formatted)), nil
+}
+
+// diffLine is a line of a unified diff without its prefix
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string // The line with its newline, which only the last line can lack
+}
+
+// unifiedDiff returns the changes turning original into formatted as a unified
+// diff of the file at name, or an empty string when they are equal
+func unifiedDiff(name, original, formatted string) string {
+	var lines []diffLine
+	for _, diff := range lineDiff(original, formatted) {
+		for _, text := range strings.SplitAfter(diff.Text, "\n") {
+			if text != "" {
+				lines = append(lines, diffLine{diff.Type, text})
+			}
+		}
+	}
+
+	// The number of lines of each version before lines[i]
+	oldBefore, newBefore := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for i, line := range lines {
+		oldBefore[i+1], newBefore[i+1] = oldBefore[i], newBefore[i]
+		if line.op != diffmatchpatch.DiffInsert {
+			oldBefore[i+1]++
+		}
+		if line.op != diffmatchpatch.DiffDelete {
+			newBefore[i+1]++
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffmatchpatch.DiffEqual {
+			i++
+			continue
+		}
+
+		// Changes separated by no more unchanged lines than the context of two
+		// hunks share a hunk
+		end := i
+		for end < len(lines) {
+			if lines[end].op != diffmatchpatch.DiffEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].op == diffmatchpatch.DiffEqual {
+				run++
+			}
+			if run == len(lines) || run-end > 2*diffContext {
+				break
+			}
+			end = run
+		}
+		start, stop := max(i-diffContext, 0), min(end+diffContext, len(lines))
+
+		if b.Len() == 0 {
+			// An absolute path, of a file outside of the working directory,
+			// follows the prefixes without doubling the slash
+			header := strings.TrimPrefix(name, "/")
+			fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", header, header)
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(oldBefore[start], oldBefore[stop]-oldBefore[start]),
+			hunkRange(newBefore[start], newBefore[stop]-newBefore[start]))
+		for _, line := range lines[start:stop] {
+			switch line.op {
+			case diffmatchpatch.DiffEqual:
+				b.WriteString(" ")
+			case diffmatchpatch.DiffDelete:
+				b.WriteString("-")
+			case diffmatchpatch.DiffInsert:
+				b.WriteString("+")
+			}
+			b.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = stop
+	}
+	return b.String()
+}
+
+// hunkRange formats the lines of one version covered by a hunk. An empty range
+// names the line before it.
+func hunkRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
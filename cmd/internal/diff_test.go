@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	original := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm"
+	formatted := "A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+
+	expected := strings.Join([]string{
+		"--- a/main.tf",
+		"+++ b/main.tf",
+		"@@ -1,4 +1,4 @@",
+		"-a",
+		"+A",
+		" b",
+		" c",
+		" d",
+		"@@ -10,4 +10,4 @@",
+		" j",
+		" k",
+		" l",
+		"-m",
+		`\ No newline at end of file`,
+		"+m",
+		"",
+	}, "\n")
+	if actual := unifiedDiff("main.tf", original, formatted); actual != expected {
+		t.Errorf("Unified diff mismatch:\nexpected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	// Changes close to each other share a hunk
+	expected = "--- a/main.tf\n+++ b/main.tf\n@@ -1,9 +1,10 @@\n+x\n a\n b\n c\n d\n e\n-f\n+F\n g\n h\n-i\n+I\n"
+	if actual := unifiedDiff("main.tf", "a\nb\nc\nd\ne\nf\ng\nh\ni\n", "x\na\nb\nc\nd\ne\nF\ng\nh\nI\n"); actual != expected {
+		t.Errorf("Unified diff mismatch:\nexpected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	// An empty range names the line before it
+	expected = "--- a/main.tf\n+++ b/main.tf\n@@ -0,0 +1 @@\n+a\n"
+	if actual := unifiedDiff("main.tf", "", "a\n"); actual != expected {
+		t.Errorf("Unified diff mismatch:\nexpected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	if actual := unifiedDiff("main.tf", "a\n", "a\n"); actual != "" {
+		t.Errorf("Expected no diff for equal content, got:\n%s", actual)
+	}
+}
+
+func TestDiffHeaderPaths(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(path, []byte("locals {\n  a=1\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := Diff([]Target{{Path: dir, Config: DefaultConfig(dir)}}, 1)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	name := strings.TrimPrefix(filepath.ToSlash(path), "/")
+	if expected := "--- a/" + name + "\n+++ b/" + name + "\n"; !strings.HasPrefix(diff, expected) {
+		t.Errorf("Diff of a file outside of the working directory should start with:\n%s\ngot:\n%s", expected, diff)
+	}
+
+	// Files in the working directory are named relative to it
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	diff, err = Diff([]Target{{Path: dir, Config: DefaultConfig(dir)}}, 1)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	if expected := "--- a/main.tf\n+++ b/main.tf\n"; !strings.HasPrefix(diff, expected) {
+		t.Errorf("Diff of a file in the working directory should start with:\n%s\ngot:\n%s", expected, diff)
+	}
+}
@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/vahid-haghighat/terralint/parser"
+	"github.com/vahid-haghighat/terralint/parser/types"
+	"github.com/vahid-haghighat/terralint/rules"
+)
+
+// fixPlan runs the rules whose fixes are applied once per module, since the
+// fixes found in one file can edit the other files of the module, like the
+// references to a renamed resource
+type fixPlan struct {
+	fix     []string
+	configs map[string]*Config // The configs of the target files by their path without symbolic links
+	modules *moduleCache
+	mu      sync.Mutex
+	planned map[string]*plannedModule
+}
+
+type plannedModule struct {
+	once   sync.Once
+	module *types.Module                 // Nil when the module doesn't parse as a whole
+	fixes  map[string][]rules.Diagnostic // The fixes editing every file, with only the edits of that file
+}
+
+// newFixPlan returns the plan of the fixes found in the target files
+func newFixPlan(targets []fileResult[*Config], fix []string) (*fixPlan, error) {
+	configs := make(map[string]*Config, len(targets))
+	for _, target := range targets {
+		path, err := filepath.EvalSymlinks(target.path)
+		if err != nil {
+			return nil, err
+		}
+		configs[path] = target.value
+	}
+
+	return &fixPlan{
+		fix:     fix,
+		configs: configs,
+		modules: &moduleCache{modules: make(map[string]*moduleLoad)},
+		planned: make(map[string]*plannedModule),
+	}, nil
+}
+
+// apply applies the planned fixes of a file to its content. A file whose
+// module doesn't parse, or whose content changed since the module was loaded,
+// only gets the fixes of the rules that don't need the module.
+func (p *fixPlan) apply(filePath string, content []byte, config *Config) ([]byte, error) {
+	planned := p.plan(filepath.Dir(filePath), config)
+	if planned.module != nil {
+		if file := planned.module.File(filePath); file != nil && bytes.Equal(file.Content, content) {
+			fixed, _, err := rules.ApplyFixes(content, planned.fixes[filePath])
+			return fixed, err
+		}
+	}
+	return fixFile(content, filePath, config, p.fix)
+}
+
+// plan returns the fixes found in the target files of the module of a
+// directory, by the rules the config of every file enables. The config of the
+// file asking decides the files the module skips.
+func (p *fixPlan) plan(dir string, config *Config) *plannedModule {
+	p.mu.Lock()
+	planned, found := p.planned[dir]
+	if !found {
+		planned = &plannedModule{}
+		p.planned[dir] = planned
+	}
+	p.mu.Unlock()
+
+	planned.once.Do(func() {
+		module, err := p.modules.load(dir, config)
+		if err != nil {
+			return
+		}
+
+		writable := p.writableFiles(module)
+
+		// A fix is applied to all of the files it edits or to none of them
+		var accepted []rules.Diagnostic
+		edits := make(map[string][]rules.Edit)
+		for _, file := range module.Files {
+			config, found := p.configs[file.Path]
+			if !found {
+				continue
+			}
+			for _, diagnostic := range rules.Run(&rules.File{Path: file.Path, Content: file.Content, Root: file.Root, Module: module}, config.fixRules(p.fix)) {
+				if diagnostic.Fix == nil || !editsFiles(writable, diagnostic.Fix.Edits) || !fitsFiles(edits, diagnostic.Fix.Edits) {
+					continue
+				}
+				for _, edit := range diagnostic.Fix.Edits {
+					edits[edit.Range.Filename] = append(edits[edit.Range.Filename], edit)
+				}
+				accepted = append(accepted, diagnostic)
+			}
+		}
+
+		planned.module = module
+		planned.fixes = make(map[string][]rules.Diagnostic)
+		for _, file := range module.Files {
+			planned.fixes[file.Path] = fixesOf(accepted, file.Path)
+		}
+	})
+	return planned
+}
+
+// writableFiles returns the files of a module the fixes can edit. Those are
+// the targets, and the other files the config of the targets includes and no
+// ignore file skips, so that a fix never touches a file the user left out.
+func (p *fixPlan) writableFiles(module *types.Module) map[string]bool {
+	var config *Config
+	writable := make(map[string]bool, len(module.Files))
+	for _, file := range module.Files {
+		if target, found := p.configs[file.Path]; found {
+			writable[file.Path] = true
+			config = target
+		}
+	}
+	if config == nil {
+		return writable
+	}
+
+	ig, err := newIgnorer(module.Dir, config)
+	if err != nil {
+		return writable
+	}
+	for _, file := range module.Files {
+		if !writable[file.Path] && config.Includes(file.Path) && !ig.excluded(file.Path, false) {
+			writable[file.Path] = true
+		}
+	}
+	return writable
+}
+
+// applyOthers applies the planned fixes to the files of the modules that
+// aren't targets, which are written without being formatted. A file that
+// changed since its module was loaded isn't written, as the fixes that edit it
+// no longer line up.
+func (p *fixPlan) applyOthers(options ApplyOptions) []fileResult[bool] {
+	var results []fileResult[bool]
+	for _, planned := range p.planned {
+		if planned.module == nil {
+			continue
+		}
+		for _, file := range planned.module.Files {
+			if _, found := p.configs[file.Path]; found || len(planned.fixes[file.Path]) == 0 {
+				continue
+			}
+			changed, err := p.applyOther(file, options)
+			results = append(results, fileResult[bool]{path: file.Path, value: changed, err: err})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].path < results[j].path
+	})
+	return results
+}
+
+func (p *fixPlan) applyOther(file *types.ModuleFile, options ApplyOptions) (bool, error) {
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		return false, err
+	}
+	original, err := os.ReadFile(file.Path)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(original, file.Content) {
+		return false, fmt.Errorf("%s: changed while being fixed", file.Path)
+	}
+
+	fixed, _, err := rules.ApplyFixes(original, p.planned[filepath.Dir(file.Path)].fixes[file.Path])
+	if err != nil {
+		return false, err
+	}
+	return writeChanged(file.Path, info.Mode().Perm(), original, fixed, options)
+}
+
+// editsFiles reports whether all of the edits are in the given files
+func editsFiles(files map[string]bool, edits []rules.Edit) bool {
+	for _, edit := range edits {
+		if !files[edit.Range.Filename] {
+			return false
+		}
+	}
+	return true
+}
+
+// fitsFiles reports whether none of the edits overlap the existing edits of
+// the file they are in
+func fitsFiles(existing map[string][]rules.Edit, edits []rules.Edit) bool {
+	for _, edit := range edits {
+		if !rules.Fits(existing[edit.Range.Filename], []rules.Edit{edit}) {
+			return false
+		}
+	}
+	return true
+}
+
+// fixFile applies the fixes of the rules with the given IDs the config enables
+// to content checked on its own, without the rest of its module
+func fixFile(content []byte, filePath string, config *Config, fix []string) ([]byte, error) {
+	root, err := parser.ParseSource(content, filePath)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics := rules.Run(&rules.File{Path: filePath, Content: content, Root: root}, config.fixRules(fix))
+	fixed, _, err := rules.ApplyFixes(content, diagnostics)
+	return fixed, err
+}
+
+// fixRules returns the enabled rules with the given IDs
+func (c *Config) fixRules(fix []string) []rules.Rule {
+	var selected []rules.Rule
+	for _, rule := range c.enabledRules() {
+		if slices.Contains(fix, rule.ID()) {
+			selected = append(selected, rule)
+		}
+	}
+	return selected
+}
+
+// fixesOf returns the diagnostics whose fixes edit the file at path, with only
+// the edits of that file
+func fixesOf(diagnostics []rules.Diagnostic, path string) []rules.Diagnostic {
+	var fixes []rules.Diagnostic
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Fix == nil {
+			continue
+		}
+		var edits []rules.Edit
+		for _, edit := range diagnostic.Fix.Edits {
+			if edit.Range.Filename == path {
+				edits = append(edits, edit)
+			}
+		}
+		if len(edits) > 0 {
+			diagnostic.Fix = &rules.Fix{Edits: edits}
+			fixes = append(fixes, diagnostic)
+		}
+	}
+	return fixes
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the files listing paths to skip in gitignore syntax
+const IgnoreFileName = ".terralintignore"
+
+// skippedDirectories are never walked into, they hold the state of tools and
+// the modules they download
+var skippedDirectories = map[string]bool{
+	".git":              true,
+	".terraform":        true,
+	".terragrunt-cache": true,
+}
+
+// ignorePattern is a line of an ignore file
+type ignorePattern struct {
+	elements []string // The slash separated elements of the pattern
+	negate   bool     // Whether the pattern starts with ! and includes paths again
+	dirOnly  bool     // Whether the pattern ends with / and only matches directories
+	anchored bool     // Whether the pattern is relative to the directory of the ignore file
+}
+
+// parseIgnore parses the patterns of an ignore file in gitignore syntax
+func parseIgnore(content string) []ignorePattern {
+	var patterns []ignorePattern
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var pattern ignorePattern
+		if strings.HasPrefix(line, "!") {
+			pattern.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			pattern.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A slash anywhere but at the end ties the pattern to the directory
+		pattern.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		line = strings.ReplaceAll(line, "[!", "[^")
+		pattern.elements = strings.Split(line, "/")
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// match reports whether the pattern matches a slash separated path relative to
+// the directory of its ignore file
+func (p ignorePattern) match(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if !p.anchored {
+		matched, _ := path.Match(p.elements[0], path.Base(rel))
+		return matched
+	}
+	return matchElements(p.elements, strings.Split(rel, "/"))
+}
+
+// ignorer decides which paths a walk skips. The ignore files of a directory
+// apply to everything below it, down from the repository the walk starts in.
+// It caches the ignore files it reads and is not safe for concurrent use.
+type ignorer struct {
+	top       string   // The directory the ignore files are read from downwards
+	fileNames []string // The names of the ignore files read in every directory
+	patterns  map[string][]ignorePattern
+}
+
+// newIgnorer returns the ignorer of a walk starting at root. The ignore files
+// are read from the root of the git repository holding root, or from root
+// when it isn't in a repository.
+func newIgnorer(root string, config *Config) (*ignorer, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(root); err == nil && !info.IsDir() {
+		root = filepath.Dir(root)
+	}
+
+	ig := &ignorer{
+		top:       root,
+		fileNames: []string{IgnoreFileName},
+		patterns:  make(map[string][]ignorePattern),
+	}
+	if config != nil && config.RespectGitignore {
+		ig.fileNames = append(ig.fileNames, ".gitignore")
+	}
+
+	for dir := root; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			ig.top = dir
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	return ig, nil
+}
+
+// load returns the patterns of the ignore files in dir
+func (ig *ignorer) load(dir string) []ignorePattern {
+	if patterns, found := ig.patterns[dir]; found {
+		return patterns
+	}
+
+	var patterns []ignorePattern
+	for _, name := range ig.fileNames {
+		// A missing or unreadable ignore file ignores nothing, like in git
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		patterns = append(patterns, parseIgnore(string(content))...)
+	}
+	ig.patterns[dir] = patterns
+	return patterns
+}
+
+// ignored reports whether the walk skips the file or directory at path. The
+// last pattern matching it wins, deeper ignore files coming last.
+func (ig *ignorer) ignored(path string, isDir bool) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if isDir && skippedDirectories[filepath.Base(path)] {
+		return true
+	}
+
+	rel, err := filepath.Rel(ig.top, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	ignored := false
+	elements := strings.Split(filepath.ToSlash(rel), "/")
+	dir := ig.top
+	for i := range elements {
+		for _, pattern := range ig.load(dir) {
+			if pattern.match(strings.Join(elements[i:], "/"), isDir) {
+				ignored = !pattern.negate
+			}
+		}
+		dir = filepath.Join(dir, elements[i])
+	}
+	return ignored
+}
+
+// excluded reports whether the path or one of the directories between it and
+// the top of the walk is skipped, which is what decides for a path named on
+// the command line
+func (ig *ignorer) excluded(path string, isDir bool) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for dir := filepath.Dir(path); strings.HasPrefix(dir, ig.top+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if ig.ignored(dir, true) {
+			return true
+		}
+	}
+	return ig.ignored(path, isDir)
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIgnorePatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		matches bool
+	}{
+		{"generated.tf", "generated.tf", false, true},
+		{"generated.tf", "modules/network/generated.tf", false, true},
+		{"*.auto.tf", "envs/prod/x.auto.tf", false, true},
+		{"/main.tf", "main.tf", false, true},
+		{"/main.tf", "modules/main.tf", false, false},
+		{"modules/*.tf", "modules/main.tf", false, true},
+		{"modules/*.tf", "modules/network/main.tf", false, false},
+		{"vendor/", "vendor", true, true},
+		{"vendor/", "vendor", false, false},
+		{"**/fixtures", "a/b/fixtures", true, true},
+		{"docs/**", "docs/examples/main.tf", false, true},
+		{"a/**/b.tf", "a/b.tf", false, true},
+		{"a/**/b.tf", "a/x/y/b.tf", false, true},
+		{"[!m]*.tf", "main.tf", false, false},
+		{`\#literal.tf`, "#literal.tf", false, true},
+	}
+
+	for _, test := range tests {
+		patterns := parseIgnore(test.pattern)
+		if len(patterns) != 1 {
+			t.Fatalf("Expected one pattern from %q, got %d", test.pattern, len(patterns))
+		}
+		if actual := patterns[0].match(test.path, test.isDir); actual != test.matches {
+			t.Errorf("Pattern %q on %q: expected %v, got %v", test.pattern, test.path, test.matches, actual)
+		}
+	}
+
+	if patterns := parseIgnore("# comment\n\n!keep.tf\n"); len(patterns) != 1 || !patterns[0].negate {
+		t.Errorf("Expected a single negated pattern, got %+v", patterns)
+	}
+}
+
+func TestProcessTreeSkipsIgnoredPaths(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".git/HEAD":                        "",
+		".gitignore":                       "scratch/\n",
+		IgnoreFileName:                     "generated/\n*.gen.tf\n!keep.gen.tf\n",
+		"main.tf":                          "",
+		"keep.gen.tf":                      "",
+		"drop.gen.tf":                      "",
+		"generated/main.tf":                "",
+		"scratch/main.tf":                  "",
+		".terraform/modules/vpc/main.tf":   "",
+		"live/.terragrunt-cache/x/main.tf": "",
+		"live/" + IgnoreFileName:           "/local.tf\n",
+		"live/main.tf":                     "",
+		"live/local.tf":                    "",
+	})
+
+	walk := func(root string, config *Config) []string {
+		results, err := processTrees([]Target{{Path: root, Config: config}}, 2, func(string, *Config) (struct{}, error) {
+			return struct{}{}, nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to process tree: %v", err)
+		}
+		var found []string
+		for _, result := range results {
+			rel, _ := filepath.Rel(config.Dir, result.path)
+			found = append(found, filepath.ToSlash(rel))
+		}
+		return found
+	}
+
+	config := DefaultConfig(root)
+	expected := []string{"keep.gen.tf", "live/main.tf", "main.tf", "scratch/main.tf"}
+	if found := walk(root, config); !reflect.DeepEqual(expected, found) {
+		t.Errorf("Walked files mismatch: expected %v, got %v", expected, found)
+	}
+
+	config.RespectGitignore = true
+	expected = []string{"keep.gen.tf", "live/main.tf", "main.tf"}
+	if found := walk(root, config); !reflect.DeepEqual(expected, found) {
+		t.Errorf("Walked files mismatch with .gitignore: expected %v, got %v", expected, found)
+	}
+
+	// Paths named directly are skipped too, using the ignore files above them
+	if found := walk(filepath.Join(root, "generated", "main.tf"), config); len(found) != 0 {
+		t.Errorf("An ignored file named directly should be skipped, got %v", found)
+	}
+	if found := walk(filepath.Join(root, "live"), config); !reflect.DeepEqual([]string{"live/main.tf"}, found) {
+		t.Errorf("Walking a subdirectory should use the ignore files of the repository, got %v", found)
+	}
+}
@@ -0,0 +1,373 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/vahid-haghighat/terralint/parser/types"
+	"github.com/vahid-haghighat/terralint/rules"
+)
+
+// defaultNamingConvention is the convention of the naming rules without a
+// configured one
+const defaultNamingConvention = "snake_case"
+
+// namingConventions are the conventions the naming rules can be configured with
+var namingConventions = map[string]*regexp.Regexp{
+	"snake_case": regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`),
+	"kebab-case": regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`),
+	"camelCase":  regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`),
+	"PascalCase": regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`),
+}
+
+// moduleMetaArguments are the arguments of module blocks that aren't variables
+// of the called module
+var moduleMetaArguments = map[string]bool{
+	"source":     true,
+	"version":    true,
+	"count":      true,
+	"for_each":   true,
+	"providers":  true,
+	"depends_on": true,
+}
+
+// name is a name chosen by the author of a file, with what it names
+type name struct {
+	value       string
+	description string
+	rng         hcl.Range
+}
+
+// namingDiagnostics returns the diagnostics of the names that don't follow a
+// convention, the default one when it's empty
+func namingDiagnostics(names []name, convention string) []rules.Diagnostic {
+	if convention == "" {
+		convention = defaultNamingConvention
+	}
+
+	var diagnostics []rules.Diagnostic
+	for _, n := range names {
+		if !namingConventions[convention].MatchString(n.value) {
+			diagnostics = append(diagnostics, rules.Diagnostic{
+				Range:   n.rng,
+				Message: fmt.Sprintf("%s %q doesn't follow the %s convention", n.description, n.value, convention),
+			})
+		}
+	}
+	return diagnostics
+}
+
+// labelNamingRule reports the block labels naming what a module declares that
+// don't follow the configured convention. The other labels, like the types of
+// resources or provisioners, are named by Terraform and the providers.
+type labelNamingRule struct {
+	convention string
+}
+
+func (r labelNamingRule) withConfig(config *Config) rules.Rule {
+	return labelNamingRule{convention: config.Rules[r.ID()].Convention}
+}
+
+func (labelNamingRule) ID() string {
+	return "label-naming"
+}
+
+func (labelNamingRule) Severity() rules.Severity {
+	return rules.SeverityWarning
+}
+
+func (labelNamingRule) Description() string {
+	return "The names of variables, outputs, resources, data sources, modules and checks follow the naming convention"
+}
+
+func (r labelNamingRule) Check(file *rules.File) []rules.Diagnostic {
+	var names []name
+	for _, child := range file.Root.Children {
+		block, ok := child.(*types.Block)
+		if !ok {
+			continue
+		}
+
+		index, description := 0, block.Type+" name"
+		switch block.Type {
+		case types.KindResource, types.KindData:
+			if len(block.Labels) != 2 {
+				continue
+			}
+			index = 1
+			if block.Type == types.KindData {
+				description = "data source name"
+			}
+		case types.KindVariable, types.KindOutput, types.KindModule, "check":
+			if len(block.Labels) != 1 {
+				continue
+			}
+		default:
+			continue
+		}
+
+		rng := block.Range
+		if label, ok := labelRange(file.Content, block, index); ok {
+			rng = label
+		}
+		names = append(names, name{value: block.Labels[index], description: description, rng: rng})
+	}
+	return namingDiagnostics(names, r.convention)
+}
+
+// attributeNamingRule reports the attribute names chosen by the author of a
+// file that don't follow the configured convention, which are the names of
+// locals, the variables passed to modules and the variables set by .tfvars
+// files. The other attributes are named by Terraform and the providers.
+type attributeNamingRule struct {
+	convention string
+}
+
+func (r attributeNamingRule) withConfig(config *Config) rules.Rule {
+	return attributeNamingRule{convention: config.Rules[r.ID()].Convention}
+}
+
+func (attributeNamingRule) ID() string {
+	return "attribute-naming"
+}
+
+func (attributeNamingRule) Severity() rules.Severity {
+	return rules.SeverityWarning
+}
+
+func (attributeNamingRule) Description() string {
+	return "The names of locals and of the variables set by modules and .tfvars files follow the naming convention"
+}
+
+func (r attributeNamingRule) Check(file *rules.File) []rules.Diagnostic {
+	var names []name
+	tfvars := filepath.Ext(file.Path) == ".tfvars"
+	for _, child := range file.Root.Children {
+		switch item := child.(type) {
+		case *types.Attribute:
+			if tfvars {
+				names = append(names, name{value: item.Name, description: "variable name", rng: item.Range})
+			}
+		case *types.Block:
+			if item.Type != "locals" && item.Type != types.KindModule {
+				continue
+			}
+			for _, nested := range item.Children {
+				attribute, ok := nested.(*types.Attribute)
+				switch {
+				case !ok:
+				case item.Type == "locals":
+					names = append(names, name{value: attribute.Name, description: "local name", rng: attribute.Range})
+				case !moduleMetaArguments[attribute.Name]:
+					names = append(names, name{value: attribute.Name, description: "module argument", rng: attribute.Range})
+				}
+			}
+		}
+	}
+	return namingDiagnostics(names, r.convention)
+}
+
+// redundantResourceNameRule reports the resources whose name repeats their
+// type, like aws_s3_bucket.s3_bucket_logs. The fix renames the resource along
+// with the references of the module, and adds a moved block so that Terraform
+// keeps the existing object.
+type redundantResourceNameRule struct{}
+
+func (redundantResourceNameRule) ID() string {
+	return "redundant-resource-name"
+}
+
+func (redundantResourceNameRule) Severity() rules.Severity {
+	return rules.SeverityWarning
+}
+
+func (redundantResourceNameRule) Description() string {
+	return "The names of resources don't repeat their type"
+}
+
+func (redundantResourceNameRule) Check(file *rules.File) []rules.Diagnostic {
+	var diagnostics []rules.Diagnostic
+	for _, child := range file.Root.Children {
+		block, ok := child.(*types.Block)
+		if !ok || block.Type != types.KindResource || len(block.Labels) != 2 {
+			continue
+		}
+		resourceType, resourceName := block.Labels[0], block.Labels[1]
+		trimmed, repeats := trimType(resourceType, resourceName)
+		if !repeats {
+			continue
+		}
+
+		diagnostic := rules.Diagnostic{
+			Range:   block.Range,
+			Message: fmt.Sprintf("the name of resource %q repeats its type, rename it to %q", resourceType+"."+resourceName, trimmed),
+		}
+		if file.Module != nil {
+			if edits, ok := rename(file.Module, file.Path, block, trimmed); ok {
+				diagnostic.Fix = &rules.Fix{Edits: edits}
+			}
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+// trimType returns the name of a resource without the words of its type, with
+// or without the provider prefix, and whether the name repeats them. A name
+// that is nothing but the type becomes this.
+func trimType(resourceType, resourceName string) (string, bool) {
+	typeWords := strings.Split(resourceType, "_")
+	nameWords := strings.Split(resourceName, "_")
+
+	candidates := [][]string{typeWords}
+	if len(typeWords) > 1 {
+		candidates = append(candidates, typeWords[1:])
+	}
+	for _, candidate := range candidates {
+		for i := 0; i+len(candidate) <= len(nameWords); i++ {
+			if !equalWords(nameWords[i:i+len(candidate)], candidate) {
+				continue
+			}
+			rest := append(append([]string{}, nameWords[:i]...), nameWords[i+len(candidate):]...)
+			if len(rest) == 0 {
+				return "this", true
+			}
+			return strings.Join(rest, "_"), true
+		}
+	}
+	return "", false
+}
+
+func equalWords(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// rename returns the edits renaming a resource, declared by block in the file
+// at path, across its module and adding the moved block recording the rename.
+// A rename that would clash with another resource, or whose labels or
+// references can't be found in the source, has no edits.
+func rename(module *types.Module, path string, block *types.Block, newName string) ([]rules.Edit, bool) {
+	resourceType, oldName := block.Labels[0], block.Labels[1]
+	if len(module.Lookup(resourceType+"."+newName)) > 0 {
+		return nil, false
+	}
+	declarations := module.Lookup(resourceType + "." + oldName)
+	file := module.File(path)
+	if len(declarations) != 1 || declarations[0].Block != block || file == nil {
+		return nil, false
+	}
+
+	label, ok := labelRange(file.Content, block, 1)
+	if !ok {
+		return nil, false
+	}
+	edits := []rules.Edit{{Range: label, NewText: newName}}
+
+	for _, binding := range module.Resolve() {
+		if binding.Declaration != declarations[0] {
+			continue
+		}
+		referencing := module.File(binding.File)
+		if referencing == nil {
+			return nil, false
+		}
+		part, ok := partRange(referencing.Content, binding.Reference, 1)
+		if !ok {
+			return nil, false
+		}
+		edits = append(edits, rules.Edit{Range: part, NewText: newName})
+	}
+
+	// The moved block goes on the line after the resource
+	end := block.Range.End
+	insertion, moved := hcl.Pos{Line: end.Line + 1, Column: 1, Byte: len(file.Content)}, "\nmoved {\n  from = %s.%s\n  to   = %s.%s\n}\n"
+	if newline := strings.IndexByte(string(file.Content[end.Byte:]), '\n'); newline >= 0 {
+		insertion.Byte = end.Byte + newline + 1
+	} else {
+		moved = "\n" + moved
+	}
+	edits = append(edits, rules.Edit{
+		Range:   hcl.Range{Filename: block.Range.Filename, Start: insertion, End: insertion},
+		NewText: fmt.Sprintf(moved, resourceType, oldName, resourceType, newName),
+	})
+	return edits, true
+}
+
+// labelRange returns the range of the text of a label of a block, without the
+// quotes around it
+func labelRange(content []byte, block *types.Block, index int) (hcl.Range, bool) {
+	offset := block.Range.Start.Byte + len(block.Type)
+	if offset > len(content) || string(content[block.Range.Start.Byte:offset]) != block.Type {
+		return hcl.Range{}, false
+	}
+
+	for i, label := range block.Labels {
+		offset = skipSpaces(content, offset)
+		start, end := offset, offset+len(label)
+		if offset < len(content) && content[offset] == '"' {
+			start, end = offset+1, offset+1+len(label)
+			if end >= len(content) || content[end] != '"' {
+				return hcl.Range{}, false
+			}
+			offset = end + 1
+		} else {
+			offset = end
+		}
+		if end > len(content) || string(content[start:end]) != label {
+			return hcl.Range{}, false
+		}
+		if i == index {
+			return byteRange(block.Range, start, end), true
+		}
+	}
+	return hcl.Range{}, false
+}
+
+// partRange returns the range of a part of a reference, like the name of a
+// resource in aws_instance.web.id
+func partRange(content []byte, reference *types.ReferenceExpr, index int) (hcl.Range, bool) {
+	rng := reference.Range()
+	offset := rng.Start.Byte
+	for i, part := range reference.Parts {
+		if i > 0 {
+			offset = skipSpaces(content, offset)
+			if offset >= len(content) || content[offset] != '.' {
+				return hcl.Range{}, false
+			}
+			offset = skipSpaces(content, offset+1)
+		}
+		end := offset + len(part)
+		if end > len(content) || string(content[offset:end]) != part {
+			return hcl.Range{}, false
+		}
+		if i == index {
+			return byteRange(rng, offset, end), true
+		}
+		offset = end
+	}
+	return hcl.Range{}, false
+}
+
+func skipSpaces(content []byte, offset int) int {
+	for offset < len(content) && (content[offset] == ' ' || content[offset] == '\t') {
+		offset++
+	}
+	return offset
+}
+
+// byteRange returns the range between two offsets on the line where within
+// starts, which labels and references don't leave
+func byteRange(within hcl.Range, start, end int) hcl.Range {
+	pos := func(offset int) hcl.Pos {
+		return hcl.Pos{Line: within.Start.Line, Column: within.Start.Column + offset - within.Start.Byte, Byte: offset}
+	}
+	return hcl.Range{Filename: within.Filename, Start: pos(start), End: pos(end)}
+}
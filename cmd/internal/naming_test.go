@@ -0,0 +1,236 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNamingRules(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.tf": `locals {
+  bucketName = "logs"
+}
+
+resource "aws_s3_bucket" "AppLogs" {
+  bucket = local.bucketName
+}
+
+module "network" {
+  source     = "./network"
+  cidr-block = "10.0.0.0/16"
+}
+
+resource "null_resource" "notify" {
+  provisioner "local-exec" {
+    command = "true"
+  }
+}
+`,
+		"terraform.tfvars": "Region = \"eu-west-1\"\n",
+	}
+	writeFiles(t, root, files)
+
+	check := func(config *Config) []string {
+		diagnostics, err := Check([]Target{{Path: root, Config: config}}, 2)
+		if err != nil {
+			t.Fatalf("Failed to check: %v", err)
+		}
+		var messages []string
+		for _, diagnostic := range diagnostics {
+			if diagnostic.Rule == "label-naming" || diagnostic.Rule == "attribute-naming" {
+				messages = append(messages, fmt.Sprintf("%s:%d:%d %s", filepath.Base(diagnostic.File), diagnostic.Range.Start.Line, diagnostic.Range.Start.Column, diagnostic.Message))
+			}
+		}
+		return messages
+	}
+
+	expected := []string{
+		`main.tf:2:3 local name "bucketName" doesn't follow the snake_case convention`,
+		`main.tf:5:27 resource name "AppLogs" doesn't follow the snake_case convention`,
+		`main.tf:11:3 module argument "cidr-block" doesn't follow the snake_case convention`,
+		`terraform.tfvars:1:1 variable name "Region" doesn't follow the snake_case convention`,
+	}
+	if messages := check(DefaultConfig(root)); !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Diagnostics mismatch:\nexpected: %v\ngot:      %v", expected, messages)
+	}
+
+	config, err := parseConfig([]byte("rule \"label-naming\" {\n  convention = \"PascalCase\"\n}\n"), ConfigFileName, root)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	expected = []string{
+		`main.tf:2:3 local name "bucketName" doesn't follow the snake_case convention`,
+		`main.tf:9:9 module name "network" doesn't follow the PascalCase convention`,
+		`main.tf:11:3 module argument "cidr-block" doesn't follow the snake_case convention`,
+		`main.tf:14:27 resource name "notify" doesn't follow the PascalCase convention`,
+		`terraform.tfvars:1:1 variable name "Region" doesn't follow the snake_case convention`,
+	}
+	if messages := check(config); !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Diagnostics mismatch with a configured convention:\nexpected: %v\ngot:      %v", expected, messages)
+	}
+}
+
+func TestTrimType(t *testing.T) {
+	tests := []struct {
+		resourceType, resourceName string
+		trimmed                    string
+		repeats                    bool
+	}{
+		{"aws_s3_bucket", "s3_bucket_logs", "logs", true},
+		{"aws_s3_bucket", "aws_s3_bucket_logs", "logs", true},
+		{"aws_route_table", "public_route_table", "public", true},
+		{"aws_instance", "instance", "this", true},
+		{"aws_s3_bucket", "bucket", "", false},
+		{"aws_instance", "web", "", false},
+	}
+
+	for _, test := range tests {
+		trimmed, repeats := trimType(test.resourceType, test.resourceName)
+		if trimmed != test.trimmed || repeats != test.repeats {
+			t.Errorf("trimType(%q, %q) = %q, %v, expected %q, %v", test.resourceType, test.resourceName, trimmed, repeats, test.trimmed, test.repeats)
+		}
+	}
+}
+
+func TestRedundantResourceName(t *testing.T) {
+	files := map[string]string{
+		"main.tf": `resource "aws_s3_bucket" "s3_bucket_logs" {
+  bucket = "logs"
+}
+
+resource "aws_s3_bucket_policy" "logs" {
+  bucket = aws_s3_bucket.s3_bucket_logs.id
+}
+`,
+		"outputs.tf": `output "bucket" {
+  description = "The bucket of the logs"
+  value       = "${aws_s3_bucket.s3_bucket_logs.bucket}-${aws_s3_bucket.s3_bucket_logs[*].id}"
+}
+`,
+	}
+	fixed := map[string]string{
+		"main.tf": `resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+moved {
+  from = aws_s3_bucket.s3_bucket_logs
+  to   = aws_s3_bucket.logs
+}
+
+resource "aws_s3_bucket_policy" "logs" {
+  bucket = aws_s3_bucket.logs.id
+}
+`,
+		"outputs.tf": `output "bucket" {
+  description = "The bucket of the logs"
+  value       = "${aws_s3_bucket.logs.bucket}-${aws_s3_bucket.logs[*].id}"
+}
+`,
+	}
+
+	// The references of the other files are renamed even when only the file
+	// declaring the resource is a target
+	for name, target := range map[string]string{"directory": "", "file": "main.tf"} {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, files)
+
+			targets := []Target{{Path: filepath.Join(root, target), Config: DefaultConfig(root)}}
+			diagnostics, err := Check(targets, 2)
+			if err != nil {
+				t.Fatalf("Failed to check: %v", err)
+			}
+			var messages []string
+			for _, diagnostic := range diagnostics {
+				if diagnostic.Rule == "redundant-resource-name" {
+					messages = append(messages, fmt.Sprintf("%s:%d %s", filepath.Base(diagnostic.File), diagnostic.Range.Start.Line, diagnostic.Message))
+				}
+			}
+			expected := []string{`main.tf:1 the name of resource "aws_s3_bucket.s3_bucket_logs" repeats its type, rename it to "logs"`}
+			if !reflect.DeepEqual(messages, expected) {
+				t.Errorf("Diagnostics mismatch:\nexpected: %v\ngot:      %v", expected, messages)
+			}
+
+			changed, err := Apply(targets, 2, ApplyOptions{Fix: []string{"redundant-resource-name"}})
+			if err != nil {
+				t.Fatalf("Failed to apply: %v", err)
+			}
+			if names := baseNames(changed); !reflect.DeepEqual(names, []string{"main.tf", "outputs.tf"}) {
+				t.Errorf("Expected both files to change, got %v", names)
+			}
+			for name, content := range fixed {
+				if actual, _ := os.ReadFile(filepath.Join(root, name)); string(actual) != content {
+					t.Errorf("%s mismatch after the rename:\nexpected:\n%s\ngot:\n%s", name, content, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestRenameLeavesSkippedFilesAlone(t *testing.T) {
+	files := map[string]string{
+		"main.tf": `resource "aws_s3_bucket" "s3_bucket_logs" {
+  bucket = "logs"
+}
+`,
+		"outputs.tf": `output "bucket" {
+  description = "The bucket of the logs"
+  value       = aws_s3_bucket.s3_bucket_logs.id
+}
+`,
+	}
+	write := func(t *testing.T, extra map[string]string) string {
+		t.Helper()
+		root := t.TempDir()
+		writeFiles(t, root, files)
+		writeFiles(t, root, extra)
+		return root
+	}
+	fix := []string{"redundant-resource-name"}
+
+	t.Run("dry run", func(t *testing.T) {
+		root := write(t, nil)
+		changed, err := Apply([]Target{{Path: filepath.Join(root, "main.tf"), Config: DefaultConfig(root)}}, 2, ApplyOptions{DryRun: true, Fix: fix})
+		if err != nil {
+			t.Fatalf("Failed to apply: %v", err)
+		}
+		if len(changed) != 2 || !changed[0].Target || changed[1].Target || filepath.Base(changed[1].Path) != "outputs.tf" {
+			t.Errorf("Expected outputs.tf to be reported as not being a target, got %+v", changed)
+		}
+	})
+
+	skipped := map[string]struct {
+		extra  map[string]string
+		config string
+	}{
+		"ignore file": {extra: map[string]string{IgnoreFileName: "outputs.tf\n"}},
+		"exclude":     {config: "exclude = [\"outputs.tf\"]\n"},
+	}
+	for name, test := range skipped {
+		t.Run(name, func(t *testing.T) {
+			root := write(t, test.extra)
+			config, err := parseConfig([]byte(test.config), ConfigFileName, root)
+			if err != nil {
+				t.Fatalf("Failed to parse config: %v", err)
+			}
+
+			changed, err := Apply([]Target{{Path: filepath.Join(root, "main.tf"), Config: config}}, 2, ApplyOptions{Fix: fix})
+			if err != nil {
+				t.Fatalf("Failed to apply: %v", err)
+			}
+			if len(changed) != 0 {
+				t.Errorf("Expected the rename to be left out, got %+v", changed)
+			}
+			for name, content := range files {
+				if actual, _ := os.ReadFile(filepath.Join(root, name)); string(actual) != content {
+					t.Errorf("%s was written:\n%s", name, actual)
+				}
+			}
+		})
+	}
+}
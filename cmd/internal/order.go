@@ -0,0 +1,319 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/vahid-haghighat/terralint/parser/types"
+	"github.com/vahid-haghighat/terralint/printer"
+)
+
+// Placement groups, in the order they end up in a body
+const (
+	placementPrepended = iota
+	placementPrependedBlock
+	placementUnordered
+	placementAppended
+)
+
+type placement struct {
+	group    int
+	location *LocationSettings
+	previous types.Body // The item that preceded this one in the source
+}
+
+type orderViolation struct {
+	item    types.Body
+	message string
+}
+
+type ordering struct {
+	config     *Config
+	move       bool // Whether the items are moved or only checked
+	placements map[types.Body]*placement
+	violations []orderViolation
+}
+
+// reorderRoot records the items of every body in the root of a file that are
+// not at the positions required by the priority lists of the config, and moves
+// them there when move is set
+func reorderRoot(root *types.Root, filePath string, config *Config, move bool) *ordering {
+	o := &ordering{config: config, move: move, placements: make(map[types.Body]*placement)}
+	root.Children = o.reorder(root.Children, config.priorities(rootSection(filePath)), "file")
+
+	sort.SliceStable(o.violations, func(i, j int) bool {
+		return itemRange(o.violations[i].item).Start.Byte < itemRange(o.violations[j].item).Start.Byte
+	})
+	return o
+}
+
+// unit is a body item together with the directives before it, or a region
+// formatting is turned off for, which moves as a whole
+type unit struct {
+	item    types.Body // The item deciding the placement of the unit
+	members []types.Body
+	frozen  bool // Whether the unit is a region formatting is turned off for
+}
+
+// units splits the children of a body into the units that get reordered
+func units(children []types.Body) []*unit {
+	var result []*unit
+	var directives []types.Body
+	for i := 0; i < len(children); i++ {
+		child := children[i]
+		directive, ok := child.(*types.FormatDirective)
+		switch {
+		case ok && directive.FormattingOff():
+			members := append(directives, child)
+			for i+1 < len(children) {
+				i++
+				members = append(members, children[i])
+				if on, ok := children[i].(*types.FormatDirective); ok && on.FormattingOn() {
+					break
+				}
+			}
+			result = append(result, &unit{item: child, members: members, frozen: true})
+			directives = nil
+		case ok:
+			directives = append(directives, child)
+		default:
+			result = append(result, &unit{item: child, members: append(directives, child)})
+			directives = nil
+		}
+	}
+	if len(directives) > 0 {
+		result = append(result, &unit{item: directives[0], members: directives})
+	}
+	return result
+}
+
+func (o *ordering) reorder(children []types.Body, lists *PriorityLists, container string) []types.Body {
+	bodyUnits := units(children)
+	for _, u := range bodyUnits {
+		if u.frozen {
+			continue
+		}
+		for _, member := range u.members {
+			if block, ok := member.(*types.Block); ok {
+				block.Children = o.reorder(block.Children, o.config.priorities(block.Type), "block")
+			}
+		}
+	}
+
+	items := make([]types.Body, len(bodyUnits))
+	for i, u := range bodyUnits {
+		p := getPlacement(u.item, lists)
+		if i > 0 {
+			previous := bodyUnits[i-1].members
+			p.previous = previous[len(previous)-1]
+		}
+		for _, member := range u.members {
+			o.placements[member] = p
+		}
+		items[i] = u.item
+	}
+
+	sorted := make([]*unit, len(bodyUnits))
+	copy(sorted, bodyUnits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return o.placements[sorted[i].item].less(o.placements[sorted[j].item])
+	})
+
+	sortedItems := make([]types.Body, len(sorted))
+	positions := make(map[types.Body]int, len(sorted))
+	result := make([]types.Body, 0, len(children))
+	for i, u := range sorted {
+		sortedItems[i] = u.item
+		positions[u.item] = i
+		result = append(result, u.members...)
+	}
+
+	listed := func(item types.Body) bool {
+		return o.placements[item].group != placementUnordered
+	}
+	for _, item := range movedItems(items, sortedItems, listed) {
+		if _, ok := item.(*types.FormatDirective); ok {
+			continue
+		}
+		o.violations = append(o.violations, orderViolation{
+			item:    item,
+			message: violationMessage(item, sortedItems[:positions[item]], container),
+		})
+	}
+
+	if !o.move {
+		return children
+	}
+	return result
+}
+
+func getPlacement(item types.Body, lists *PriorityLists) *placement {
+	name, isBlock := itemName(item)
+
+	if !isBlock {
+		if location := getLocation(name, lists.PrependedAttributes); location.OuterIndex != math.MaxInt {
+			return &placement{group: placementPrepended, location: location}
+		}
+	} else {
+		if location := getLocation(name, lists.PrependedBlocks); location.OuterIndex != math.MaxInt {
+			return &placement{group: placementPrependedBlock, location: location}
+		}
+	}
+
+	// Blocks like lifecycle are listed next to the attributes kept at the end
+	if location := getLocation(name, lists.AppendedAttributes); location.OuterIndex != math.MaxInt {
+		return &placement{group: placementAppended, location: location}
+	}
+
+	return &placement{group: placementUnordered, location: getLocation("", nil)}
+}
+
+func (p *placement) less(other *placement) bool {
+	if p.group != other.group {
+		return p.group < other.group
+	}
+
+	switch p.group {
+	case placementPrepended, placementPrependedBlock:
+		if p.location.OuterIndex != other.location.OuterIndex {
+			return p.location.OuterIndex < other.location.OuterIndex
+		}
+		return p.location.InnerIndex < other.location.InnerIndex
+	case placementAppended:
+		// Lower indexes are closer to the end of the body
+		if p.location.OuterIndex != other.location.OuterIndex {
+			return p.location.OuterIndex > other.location.OuterIndex
+		}
+		return p.location.InnerIndex < other.location.InnerIndex
+	}
+	return false
+}
+
+func (p *placement) sameGroup(other *placement) bool {
+	return p.group == other.group && p.location.OuterIndex == other.location.OuterIndex
+}
+
+// blankLines enforces the new line counts of the priority lists between two
+// sibling items and keeps the source spacing for everything else
+func (o *ordering) blankLines(_ *types.Block, previous, current types.Body) int {
+	previousPlacement, currentPlacement := o.placements[previous], o.placements[current]
+	if previousPlacement == nil || currentPlacement == nil || previousPlacement == currentPlacement {
+		return -1
+	}
+
+	required := 0
+	if !previousPlacement.sameGroup(currentPlacement) {
+		if previousPlacement.group == placementPrepended || previousPlacement.group == placementPrependedBlock {
+			required = max(required, previousPlacement.location.NewLineCountAfter)
+		}
+		if currentPlacement.group == placementAppended {
+			required = max(required, currentPlacement.location.NewlineCountBefore)
+		}
+	}
+	if required > 0 {
+		return required
+	}
+
+	// Items that were not next to each other keep the spacing that preceded
+	// the current item in the source
+	if currentPlacement.previous != previous {
+		if currentPlacement.previous == nil {
+			return 0
+		}
+		return printer.SourceBlankLines(currentPlacement.previous, current)
+	}
+	return -1
+}
+
+// movedItems returns the items that have to move to turn the original order
+// into the sorted one, which is everything outside the heaviest sequence of
+// items that are already in relative order. The items that aren't listed weigh
+// more than all of the listed ones together, so that the listed items moving
+// past them are reported rather than the items they pass.
+func movedItems(original, sorted []types.Body, listed func(types.Body) bool) []types.Body {
+	positions := make(map[types.Body]int, len(sorted))
+	for i, item := range sorted {
+		positions[item] = i
+	}
+	weight := func(item types.Body) int {
+		if listed(item) {
+			return 1
+		}
+		return len(original) + 1
+	}
+
+	weights := make([]int, len(original))
+	previous := make([]int, len(original))
+	best := -1
+	for i, item := range original {
+		weights[i], previous[i] = weight(item), -1
+		for j := 0; j < i; j++ {
+			if positions[original[j]] < positions[item] && weights[j]+weight(item) > weights[i] {
+				weights[i], previous[i] = weights[j]+weight(item), j
+			}
+		}
+		if best < 0 || weights[i] > weights[best] {
+			best = i
+		}
+	}
+
+	inOrder := make(map[types.Body]bool, len(original))
+	for i := best; i >= 0; i = previous[i] {
+		inOrder[original[i]] = true
+	}
+
+	var moved []types.Body
+	for _, item := range original {
+		if !inOrder[item] {
+			moved = append(moved, item)
+		}
+	}
+	return moved
+}
+
+func itemRange(item types.Body) hcl.Range {
+	switch i := item.(type) {
+	case *types.Block:
+		return i.Range
+	case *types.Attribute:
+		return i.Range
+	}
+	return hcl.Range{}
+}
+
+func itemName(item types.Body) (string, bool) {
+	switch i := item.(type) {
+	case *types.Attribute:
+		return i.Name, false
+	case *types.Block:
+		return i.Type, true
+	}
+	return "", false
+}
+
+func itemDescription(item types.Body) string {
+	switch i := item.(type) {
+	case *types.Attribute:
+		return fmt.Sprintf("attribute %q", i.Name)
+	case *types.Block:
+		if len(i.Labels) > 0 {
+			return fmt.Sprintf("block %q", i.Type+" "+strings.Join(i.Labels, " "))
+		}
+		return fmt.Sprintf("block %q", i.Type)
+	}
+	return item.BodyType()
+}
+
+// violationMessage tells where a moved item belongs, which is after the last
+// of the items sorted before it that isn't a directive
+func violationMessage(item types.Body, before []types.Body, container string) string {
+	for i := len(before) - 1; i >= 0; i-- {
+		if _, ok := before[i].(*types.FormatDirective); !ok {
+			return fmt.Sprintf("%s must come after %s", itemDescription(item), itemDescription(before[i]))
+		}
+	}
+	return fmt.Sprintf("%s must come first in the %s", itemDescription(item), container)
+}
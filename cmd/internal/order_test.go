@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/vahid-haghighat/terralint/rules"
+)
+
+func TestFormatSourceKeepsDirectives(t *testing.T) {
+	input := `resource "a" "b" {
+  tags = {}
+  # terralint-ignore format
+  name = "x"
+
+  # terralint:format off
+  matrix   = [1, 0,
+              0, 1]
+  lifecycle {}
+  # terralint:format on
+  count = 1
+}
+`
+	expected := `resource "a" "b" {
+  count = 1
+
+  # terralint-ignore format
+  name = "x"
+
+  # terralint:format off
+  matrix   = [1, 0,
+              0, 1]
+  lifecycle {}
+  # terralint:format on
+
+  tags = {}
+}
+`
+
+	formatted, err := FormatSource([]byte(input), "main.tf", nil)
+	if err != nil {
+		t.Fatalf("Failed to format: %v", err)
+	}
+	if string(formatted) != expected {
+		t.Errorf("Formatted output mismatch:\nexpected:\n%s\ngot:\n%s", expected, formatted)
+	}
+}
+
+func TestReorder(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		File       string // main.tf when empty
+		Input      string
+		Expected   string
+		Violations []string
+	}{
+		{
+			Name: "Prepends and appends the listed attributes",
+			Input: `module "m" {
+  depends_on = []
+  name       = "x"
+  version    = "1.0"
+  source     = "./m"
+}
+`,
+			Expected: `module "m" {
+  source  = "./m"
+  version = "1.0"
+
+  name = "x"
+
+  depends_on = []
+}
+`,
+			Violations: []string{
+				`2:3 attribute "depends_on" must come after attribute "name"`,
+				`4:3 attribute "version" must come after attribute "source"`,
+				`5:3 attribute "source" must come first in the block`,
+			},
+		},
+		{
+			Name: "Orders the prepended and appended groups by their lists",
+			Input: `resource "aws_instance" "web" {
+  tags = {}
+  ami  = "x"
+  lifecycle {}
+  depends_on    = []
+  provider      = aws
+  for_each      = {}
+  count         = 1
+  instance_type = "t"
+}
+`,
+			Expected: `resource "aws_instance" "web" {
+  count = 1
+
+  for_each = {}
+
+  provider = aws
+
+  ami           = "x"
+  instance_type = "t"
+
+  lifecycle {}
+
+  tags = {}
+
+  depends_on = []
+}
+`,
+			Violations: []string{
+				`2:3 attribute "tags" must come after block "lifecycle"`,
+				`4:3 block "lifecycle" must come after attribute "instance_type"`,
+				`5:3 attribute "depends_on" must come after attribute "tags"`,
+				`6:3 attribute "provider" must come after attribute "for_each"`,
+				`7:3 attribute "for_each" must come after attribute "count"`,
+				`8:3 attribute "count" must come first in the block`,
+			},
+		},
+		{
+			Name:     "Puts terraform and locals first at the root",
+			Input:    "variable \"a\" {}\n\nlocals {}\n\nterraform {}\n",
+			Expected: "terraform {}\n\nlocals {}\n\nvariable \"a\" {}\n",
+			Violations: []string{
+				`3:1 block "locals" must come after block "terraform"`,
+				`5:1 block "terraform" must come first in the file`,
+			},
+		},
+		{
+			Name: "Enforces the new line counts between groups",
+			Input: `resource "a" "b" {
+  count = 1
+  name  = "x"
+
+
+  other = "y"
+  tags  = {}
+}
+`,
+			Expected: `resource "a" "b" {
+  count = 1
+
+  name = "x"
+
+  other = "y"
+
+  tags = {}
+}
+`,
+		},
+		{
+			Name: "Moves leading comments with their item",
+			Input: `resource "a" "b" {
+  name = "x"
+
+  # One per zone
+  count = 3 # three
+}
+`,
+			Expected: `resource "a" "b" {
+  # One per zone
+  count = 3 # three
+
+  name = "x"
+}
+`,
+			Violations: []string{
+				`5:3 attribute "count" must come first in the block`,
+			},
+		},
+		{
+			Name:     "Keeps the file header at the top",
+			Input:    "# Copyright header\n\nresource \"a\" \"b\" {}\n\nlocals {}\n",
+			Expected: "# Copyright header\n\nlocals {}\n\nresource \"a\" \"b\" {}\n",
+			Violations: []string{
+				`5:1 block "locals" must come first in the file`,
+			},
+		},
+		{
+			Name:     "Keeps the order of tfvars files",
+			File:     "prod.tfvars",
+			Input:    "tags = {}\nregion = \"x\"\ncount = 1\n",
+			Expected: "tags   = {}\nregion = \"x\"\ncount  = 1\n",
+		},
+		{
+			Name:     "Leaves ordered bodies alone",
+			Input:    "resource \"a\" \"b\" {\n  count = 1\n\n  name = \"x\"\n  id   = \"y\"\n\n  tags = {}\n}\n",
+			Expected: "resource \"a\" \"b\" {\n  count = 1\n\n  name = \"x\"\n  id   = \"y\"\n\n  tags = {}\n}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			file := tc.File
+			if file == "" {
+				file = "main.tf"
+			}
+			formatted, err := FormatSource([]byte(tc.Input), file, nil)
+			if err != nil {
+				t.Fatalf("Failed to format: %v", err)
+			}
+			if string(formatted) != tc.Expected {
+				t.Errorf("Formatted output mismatch:\nexpected:\n%s\ngot:\n%s", tc.Expected, formatted)
+			}
+
+			diagnostics, err := CheckSource([]byte(tc.Input), file, DefaultConfig(t.TempDir()))
+			if err != nil {
+				t.Fatalf("Failed to check: %v", err)
+			}
+			var violations []string
+			for _, diagnostic := range diagnostics {
+				switch diagnostic.Rule {
+				case "format":
+					if diagnostic.Severity != rules.SeverityWarning {
+						t.Errorf("Formatting differences should be warnings, got %s", diagnostic.Severity)
+					}
+				case "order":
+					violations = append(violations, fmt.Sprintf("%d:%d %s", diagnostic.Range.Start.Line, diagnostic.Range.Start.Column, diagnostic.Message))
+				}
+			}
+			if !reflect.DeepEqual(violations, tc.Violations) {
+				t.Errorf("Violations mismatch:\nexpected: %q\ngot:      %q", tc.Violations, violations)
+			}
+		})
+	}
+}
+
+func TestFormatLeavesOrderToTheOrderRule(t *testing.T) {
+	input := "# Copyright header\n\nresource \"a\" \"b\" {\n  name = \"x\"\n  count = 1\n}\n\nlocals {}\n"
+	diagnostics, err := CheckSource([]byte(input), "main.tf", DefaultConfig(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+
+	var found []string
+	for _, diagnostic := range diagnostics {
+		found = append(found, fmt.Sprintf("%d:%d %s", diagnostic.Range.Start.Line, diagnostic.Range.Start.Column, diagnostic.Rule))
+	}
+	expected := []string{"4:1 format", "5:3 order", "8:1 order"}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Diagnostics mismatch:\nexpected: %q\ngot:      %q", expected, found)
+	}
+}
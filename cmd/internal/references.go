@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/vahid-haghighat/terralint/parser/types"
+	"github.com/vahid-haghighat/terralint/rules"
+)
+
+// undefinedReferenceRule reports the references that don't resolve to anything
+// declared in the module of the file
+type undefinedReferenceRule struct{}
+
+func (undefinedReferenceRule) ID() string {
+	return "undefined-reference"
+}
+
+func (undefinedReferenceRule) Severity() rules.Severity {
+	return rules.SeverityError
+}
+
+func (undefinedReferenceRule) Description() string {
+	return "References resolve to a declaration of the module, an iterator or a built-in object"
+}
+
+func (undefinedReferenceRule) Check(file *rules.File) []rules.Diagnostic {
+	if file.Module == nil {
+		return nil
+	}
+
+	var diagnostics []rules.Diagnostic
+	for _, binding := range file.Module.Resolve() {
+		if binding.File != file.Path || binding.Kind != types.BindingUnresolved {
+			continue
+		}
+		diagnostics = append(diagnostics, rules.Diagnostic{
+			Range:   binding.Reference.Range(),
+			Message: binding.Problem,
+		})
+	}
+	return diagnostics
+}
+
+// unusedDeclarationRule reports the variables, locals, data sources and provider
+// aliases of a module that nothing refers to. The fix removes them.
+type unusedDeclarationRule struct{}
+
+func (unusedDeclarationRule) ID() string {
+	return "unused-declaration"
+}
+
+func (unusedDeclarationRule) Severity() rules.Severity {
+	return rules.SeverityWarning
+}
+
+func (unusedDeclarationRule) Description() string {
+	return "Variables, locals, data sources and provider aliases are referenced somewhere in the module"
+}
+
+func (unusedDeclarationRule) Check(file *rules.File) []rules.Diagnostic {
+	if file.Module == nil {
+		return nil
+	}
+
+	counts := file.Module.ReferenceCounts()
+	var diagnostics []rules.Diagnostic
+	for _, declaration := range file.Module.Declarations() {
+		if declaration.File != file.Path || counts[declaration] > 0 {
+			continue
+		}
+
+		var description string
+		switch declaration.Kind {
+		case types.KindVariable:
+			description = fmt.Sprintf("variable %q", declaration.Name)
+		case types.KindLocal:
+			description = fmt.Sprintf("local value %q", declaration.Name)
+		case types.KindData:
+			description = fmt.Sprintf("data source %q", declaration.Type+"."+declaration.Name)
+		case types.KindProvider:
+			// The default configuration of a provider is used implicitly, and
+			// the configuration aliases are passed in by the calling module
+			if declaration.Name == "" || declaration.Attribute != nil {
+				continue
+			}
+			description = fmt.Sprintf("provider configuration %q", declaration.Type+"."+declaration.Name)
+		default:
+			continue
+		}
+
+		diagnostics = append(diagnostics, rules.Diagnostic{
+			Range:   declaration.Range,
+			Message: description + " is declared but not used",
+			Fix:     &rules.Fix{Edits: []rules.Edit{removal(file.Content, declaration)}},
+		})
+	}
+	return diagnostics
+}
+
+// removal returns the edit removing a declaration along with the comments
+// attached to it and the lines it leaves empty. A local that is alone in its
+// locals block takes the block with it.
+func removal(content []byte, declaration *types.Declaration) rules.Edit {
+	rng, comments := declaration.Range, declaration.Block.Comments
+	if declaration.Attribute != nil {
+		comments = declaration.Attribute.Comments
+		if len(declaration.Block.Children) == 1 {
+			rng, comments = declaration.Block.Range, declaration.Block.Comments
+		}
+	}
+
+	start, end := rng.Start, rng.End
+	if len(comments.Leading) > 0 {
+		start = comments.Leading[0].Range.Start
+	}
+	for _, comment := range comments.Trailing {
+		if comment.Range.End.Byte > end.Byte {
+			end = comment.Range.End
+		}
+	}
+
+	// Take the indentation before and the line break after
+	lineStart := start.Byte
+	for lineStart > 0 && (content[lineStart-1] == ' ' || content[lineStart-1] == '\t') {
+		lineStart--
+	}
+	if lineStart == 0 || content[lineStart-1] == '\n' {
+		start.Column -= start.Byte - lineStart
+		start.Byte = lineStart
+	}
+	lineEnd := end.Byte
+	for lineEnd < len(content) && (content[lineEnd] == ' ' || content[lineEnd] == '\t' || content[lineEnd] == '\r') {
+		lineEnd++
+	}
+	if lineEnd < len(content) && content[lineEnd] == '\n' {
+		end = hcl.Pos{Line: end.Line + 1, Column: 1, Byte: lineEnd + 1}
+	}
+
+	return rules.Edit{Range: hcl.Range{Filename: rng.Filename, Start: start, End: end}}
+}
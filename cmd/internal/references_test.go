@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUndefinedReferences(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"variables.tf": "variable \"region\" {\n  type        = string\n  description = \"The region\"\n}\n",
+		"main.tf":      "provider \"aws\" {\n  region = var.region\n  zone   = var.zone\n}\n",
+	}
+	writeFiles(t, root, files)
+
+	diagnostics, err := Check([]Target{{Path: root, Config: DefaultConfig(root)}}, 2)
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected a single diagnostic, got %v", diagnostics)
+	}
+	diagnostic := diagnostics[0]
+	if diagnostic.Rule != "undefined-reference" || diagnostic.File != filepath.Join(root, "main.tf") ||
+		diagnostic.Range.Start.Line != 3 || diagnostic.Message != `variable "zone" is not declared` {
+		t.Errorf("Unexpected diagnostic: %v", diagnostic)
+	}
+
+	// Content checked on its own doesn't know the declarations of the module
+	diagnostics, err = CheckSource([]byte(files["main.tf"]), "<stdin>", DefaultConfig(root))
+	if err != nil {
+		t.Fatalf("Failed to check source: %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics without a module, got %v", diagnostics)
+	}
+}
+
+// TestModuleSkippedFiles checks that the files the config excludes or an
+// ignore file skips still declare things, and don't turn off the rules that
+// need the module when they don't parse
+func TestModuleSkippedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"variables.tf": "variable \"region\" {\n  type        = string\n  description = \"The region\"\n}\n",
+		"generated.tf": "variable \"zone\" {}\n",
+		"broken.tf":    "locals {\n",
+		"main.tf":      "provider \"aws\" {\n  region = var.region\n  zone   = var.zone\n  name   = var.name\n}\n",
+		IgnoreFileName: "broken.tf\n",
+	})
+	config := DefaultConfig(root)
+	config.Exclude = []string{"generated.tf"}
+
+	diagnostics, err := Check([]Target{{Path: root, Config: config}}, 2)
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+	var found []string
+	for _, diagnostic := range diagnostics {
+		found = append(found, fmt.Sprintf("%s:%d %s", filepath.Base(diagnostic.File), diagnostic.Range.Start.Line, diagnostic.Message))
+	}
+	expected := []string{`main.tf:4 variable "name" is not declared`}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Diagnostics mismatch:\nexpected: %q\ngot:      %q", expected, found)
+	}
+}
+
+func TestUnusedDeclarations(t *testing.T) {
+	root := t.TempDir()
+	versions := "terraform {\n  required_providers {\n    aws = {\n      source                = \"hashicorp/aws\"\n      configuration_aliases = [aws.west]\n    }\n  }\n}\n"
+	files := map[string]string{
+		"variables.tf": `variable "used" {}
+
+# Only checked by itself
+variable "unused" {
+  validation {
+    condition     = var.unused != ""
+    error_message = "empty"
+  }
+}
+`,
+		"main.tf": `provider "aws" {
+  alias = "unused"
+}
+
+locals {
+  name = var.used
+  tags = {} # nobody reads them
+}
+
+data "aws_region" "current" {}
+
+output "name" {
+  value = local.name
+}
+`,
+		// The calling module passes in the configuration aliases
+		"versions.tf": versions,
+	}
+	writeFiles(t, root, files)
+
+	targets := []Target{{Path: root, Config: DefaultConfig(root)}}
+	diagnostics, err := Check(targets, 2)
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+	var messages []string
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Rule == "unused-declaration" {
+			messages = append(messages, fmt.Sprintf("%s:%d %s", filepath.Base(diagnostic.File), diagnostic.Range.Start.Line, diagnostic.Message))
+		}
+	}
+	expected := []string{
+		`main.tf:1 provider configuration "aws.unused" is declared but not used`,
+		`main.tf:7 local value "tags" is declared but not used`,
+		`main.tf:10 data source "aws_region.current" is declared but not used`,
+		`variables.tf:4 variable "unused" is declared but not used`,
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Diagnostics mismatch:\nexpected: %v\ngot:      %v", expected, messages)
+	}
+
+	if _, err := Apply(targets, 2, ApplyOptions{Fix: []string{"unused-declaration"}}); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+	fixed := map[string]string{
+		"variables.tf": "variable \"used\" {}\n",
+		"main.tf":      "locals {\n  name = var.used\n}\n\noutput \"name\" {\n  value = local.name\n}\n",
+		"versions.tf":  versions,
+	}
+	for name, content := range fixed {
+		if actual, _ := os.ReadFile(filepath.Join(root, name)); string(actual) != content {
+			t.Errorf("%s mismatch after removing the unused declarations:\nexpected:\n%s\ngot:\n%s", name, content, actual)
+		}
+	}
+}
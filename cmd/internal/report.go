@@ -0,0 +1,395 @@
+package internal
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vahid-haghighat/terralint/rules"
+	"github.com/vahid-haghighat/terralint/version"
+)
+
+// Reporter writes the diagnostics of a run in one output format
+type Reporter func(w io.Writer, diagnostics []rules.Diagnostic) error
+
+var reporters = map[string]Reporter{
+	"text":       writeText,
+	"json":       writeJSON,
+	"sarif":      writeSARIF,
+	"checkstyle": writeCheckstyle,
+	"junit":      writeJUnit,
+	"github":     writeGitHub,
+}
+
+// ReportFormats returns the names of the output formats
+func ReportFormats() []string {
+	formats := make([]string, 0, len(reporters))
+	for format := range reporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// GetReporter returns the reporter of an output format
+func GetReporter(format string) (Reporter, error) {
+	reporter, found := reporters[format]
+	if !found {
+		return nil, fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(ReportFormats(), ", "))
+	}
+	return reporter, nil
+}
+
+// displayPath returns path relative to the working directory when it's inside
+// of it, with forward slashes
+func displayPath(path string) string {
+	if wd, err := os.Getwd(); err == nil && filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(wd, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// byFile groups the diagnostics by file in the order the files first appear
+func byFile(diagnostics []rules.Diagnostic) ([]string, map[string][]rules.Diagnostic) {
+	var files []string
+	grouped := make(map[string][]rules.Diagnostic)
+	for _, diagnostic := range diagnostics {
+		file := displayPath(diagnostic.File)
+		if _, found := grouped[file]; !found {
+			files = append(files, file)
+		}
+		grouped[file] = append(grouped[file], diagnostic)
+	}
+	return files, grouped
+}
+
+func writeText(w io.Writer, diagnostics []rules.Diagnostic) error {
+	for _, diagnostic := range diagnostics {
+		diagnostic.File = displayPath(diagnostic.File)
+		if _, err := fmt.Fprintln(w, diagnostic.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type jsonDiagnostic struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"end_line"`
+	EndColumn int    `json:"end_column"`
+	Rule      string `json:"rule"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+	Fixable   bool   `json:"fixable"`
+}
+
+func writeJSON(w io.Writer, diagnostics []rules.Diagnostic) error {
+	results := make([]jsonDiagnostic, 0, len(diagnostics))
+	for _, diagnostic := range diagnostics {
+		results = append(results, jsonDiagnostic{
+			File:      displayPath(diagnostic.File),
+			Line:      diagnostic.Range.Start.Line,
+			Column:    diagnostic.Range.Start.Column,
+			EndLine:   diagnostic.Range.End.Line,
+			EndColumn: diagnostic.Range.End.Column,
+			Rule:      diagnostic.Rule,
+			Severity:  diagnostic.Severity.String(),
+			Message:   diagnostic.Message,
+			Fixable:   diagnostic.Fix != nil,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// SARIF 2.1.0, of which only the parts code scanning reads are written
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+func sarifLevel(severity rules.Severity) string {
+	switch severity {
+	case rules.SeverityError:
+		return "error"
+	case rules.SeverityWarning:
+		return "warning"
+	}
+	return "note"
+}
+
+func writeSARIF(w io.Writer, diagnostics []rules.Diagnostic) error {
+	driver := sarifDriver{
+		Name:           "terralint",
+		Version:        strings.TrimSpace(version.Version),
+		InformationURI: "https://github.com/vahid-haghighat/terralint",
+		Rules:          []sarifRule{},
+	}
+	for _, rule := range rules.All() {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   rule.ID(),
+			ShortDescription:     sarifMessage{Text: rule.Description()},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rule.Severity())},
+		})
+	}
+	driver.Rules = append(driver.Rules, sarifRule{
+		ID:                   parseRuleID,
+		ShortDescription:     sarifMessage{Text: "Files parse as HCL"},
+		DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rules.SeverityError)},
+	})
+
+	results := make([]sarifResult, 0, len(diagnostics))
+	for _, diagnostic := range diagnostics {
+		results = append(results, sarifResult{
+			RuleID:  diagnostic.Rule,
+			Level:   sarifLevel(diagnostic.Severity),
+			Message: sarifMessage{Text: diagnostic.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: displayPath(diagnostic.File)},
+					Region: sarifRegion{
+						StartLine:   diagnostic.Range.Start.Line,
+						StartColumn: diagnostic.Range.Start.Column,
+						EndLine:     diagnostic.Range.End.Line,
+						EndColumn:   diagnostic.Range.End.Column,
+					},
+				},
+			}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+func writeCheckstyle(w io.Writer, diagnostics []rules.Diagnostic) error {
+	report := checkstyleReport{Version: "4.3"}
+	files, grouped := byFile(diagnostics)
+	for _, file := range files {
+		checkstyle := checkstyleFile{Name: file}
+		for _, diagnostic := range grouped[file] {
+			checkstyle.Errors = append(checkstyle.Errors, checkstyleError{
+				Line:     diagnostic.Range.Start.Line,
+				Column:   diagnostic.Range.Start.Column,
+				Severity: diagnostic.Severity.String(),
+				Message:  diagnostic.Message,
+				Source:   "terralint." + diagnostic.Rule,
+			})
+		}
+		report.Files = append(report.Files, checkstyle)
+	}
+	return writeXML(w, report)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string       `xml:"name,attr"`
+	ClassName string       `xml:"classname,attr"`
+	Failure   junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit reports every diagnostic as a failed test case of the suite of its file
+func writeJUnit(w io.Writer, diagnostics []rules.Diagnostic) error {
+	report := junitTestSuites{Name: "terralint"}
+	files, grouped := byFile(diagnostics)
+	for _, file := range files {
+		suite := junitTestSuite{Name: file}
+		for _, diagnostic := range grouped[file] {
+			diagnostic.File = file
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("%s:%d:%d %s", file, diagnostic.Range.Start.Line, diagnostic.Range.Start.Column, diagnostic.Rule),
+				ClassName: diagnostic.Rule,
+				Failure: junitFailure{
+					Message: diagnostic.Message,
+					Type:    diagnostic.Severity.String(),
+					Text:    diagnostic.String(),
+				},
+			})
+		}
+		suite.Tests, suite.Failures = len(suite.Cases), len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+	return writeXML(w, report)
+}
+
+func writeXML(w io.Writer, report any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+var (
+	githubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// writeGitHub writes the diagnostics as GitHub Actions workflow commands,
+// which show up as annotations of the files
+func writeGitHub(w io.Writer, diagnostics []rules.Diagnostic) error {
+	for _, diagnostic := range diagnostics {
+		command := "notice"
+		switch diagnostic.Severity {
+		case rules.SeverityError:
+			command = "error"
+		case rules.SeverityWarning:
+			command = "warning"
+		}
+
+		_, err := fmt.Fprintf(w, "::%s file=%s,line=%d,col=%d,endLine=%d,endColumn=%d,title=%s::%s\n",
+			command,
+			githubPropertyEscaper.Replace(displayPath(diagnostic.File)),
+			diagnostic.Range.Start.Line,
+			diagnostic.Range.Start.Column,
+			diagnostic.Range.End.Line,
+			diagnostic.Range.End.Column,
+			githubPropertyEscaper.Replace("terralint "+diagnostic.Rule),
+			githubDataEscaper.Replace(diagnostic.Message),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Thresholds decide which diagnostics fail a run
+type Thresholds struct {
+	FailOn      rules.Severity // Diagnostics at least this severe fail the run
+	MaxWarnings int            // More warnings than this fail the run, unless it's negative
+}
+
+// Exceeded reports whether the diagnostics fail a run
+func (t Thresholds) Exceeded(diagnostics []rules.Diagnostic) bool {
+	warnings := 0
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity >= t.FailOn {
+			return true
+		}
+		if diagnostic.Severity == rules.SeverityWarning {
+			warnings++
+		}
+	}
+	return t.MaxWarnings >= 0 && warnings > t.MaxWarnings
+}
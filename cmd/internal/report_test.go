@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/vahid-haghighat/terralint/rules"
+)
+
+func reportDiagnostics() []rules.Diagnostic {
+	return []rules.Diagnostic{
+		{
+			File:     "modules/network/main.tf",
+			Range:    hcl.Range{Start: hcl.Pos{Line: 2, Column: 3}, End: hcl.Pos{Line: 2, Column: 12}},
+			Rule:     "order",
+			Severity: rules.SeverityError,
+			Message:  `attribute "x" is out of order`,
+		},
+		{
+			File:     "main.tf",
+			Range:    hcl.Range{Start: hcl.Pos{Line: 5, Column: 1}, End: hcl.Pos{Line: 6, Column: 1}},
+			Rule:     "format",
+			Severity: rules.SeverityWarning,
+			Message:  "not formatted, see: docs",
+			Fix:      &rules.Fix{},
+		},
+	}
+}
+
+func report(t *testing.T, format string) []byte {
+	t.Helper()
+
+	reporter, err := GetReporter(format)
+	if err != nil {
+		t.Fatalf("Failed to get reporter: %v", err)
+	}
+	var out bytes.Buffer
+	if err := reporter(&out, reportDiagnostics()); err != nil {
+		t.Fatalf("Failed to write %s report: %v", format, err)
+	}
+	return out.Bytes()
+}
+
+func TestTextAndGitHubReports(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: "text",
+			expected: "modules/network/main.tf:2:3: error: attribute \"x\" is out of order (order)\n" +
+				"main.tf:5:1: warning: not formatted, see: docs (format)\n",
+		},
+		{
+			format: "github",
+			expected: "::error file=modules/network/main.tf,line=2,col=3,endLine=2,endColumn=12,title=terralint order::attribute \"x\" is out of order\n" +
+				"::warning file=main.tf,line=5,col=1,endLine=6,endColumn=1,title=terralint format::not formatted, see: docs\n",
+		},
+	}
+
+	for _, test := range tests {
+		if actual := string(report(t, test.format)); actual != test.expected {
+			t.Errorf("%s report mismatch:\nexpected:\n%s\ngot:\n%s", test.format, test.expected, actual)
+		}
+	}
+}
+
+func TestJSONReport(t *testing.T) {
+	var results []jsonDiagnostic
+	if err := json.Unmarshal(report(t, "json"), &results); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+
+	expected := jsonDiagnostic{
+		File: "main.tf", Line: 5, Column: 1, EndLine: 6, EndColumn: 1,
+		Rule: "format", Severity: "warning", Message: "not formatted, see: docs", Fixable: true,
+	}
+	if len(results) != 2 || results[1] != expected {
+		t.Errorf("JSON report mismatch: expected %+v as the second result, got %+v", expected, results)
+	}
+}
+
+func TestSARIFReport(t *testing.T) {
+	var log sarifLog
+	if err := json.Unmarshal(report(t, "sarif"), &log); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 2 {
+		t.Fatalf("Unexpected SARIF log: %+v", log)
+	}
+	result := log.Runs[0].Results[1]
+	location := result.Locations[0].PhysicalLocation
+	if result.RuleID != "format" || result.Level != "warning" || location.ArtifactLocation.URI != "main.tf" || location.Region.StartLine != 5 {
+		t.Errorf("SARIF result mismatch: %+v", result)
+	}
+}
+
+func TestXMLReports(t *testing.T) {
+	var checkstyle checkstyleReport
+	if err := xml.Unmarshal(report(t, "checkstyle"), &checkstyle); err != nil {
+		t.Fatalf("Checkstyle report is not valid XML: %v", err)
+	}
+	if len(checkstyle.Files) != 2 || checkstyle.Files[0].Name != "modules/network/main.tf" {
+		t.Fatalf("Checkstyle files mismatch: %+v", checkstyle.Files)
+	}
+	if e := checkstyle.Files[1].Errors[0]; e.Line != 5 || e.Column != 1 || e.Severity != "warning" || e.Source != "terralint.format" {
+		t.Errorf("Checkstyle error mismatch: %+v", e)
+	}
+
+	var junit junitTestSuites
+	if err := xml.Unmarshal(report(t, "junit"), &junit); err != nil {
+		t.Fatalf("JUnit report is not valid XML: %v", err)
+	}
+	if junit.Tests != 2 || junit.Failures != 2 || len(junit.Suites) != 2 {
+		t.Fatalf("JUnit totals mismatch: %+v", junit)
+	}
+	if failure := junit.Suites[0].Cases[0].Failure; failure.Type != "error" || failure.Message != `attribute "x" is out of order` {
+		t.Errorf("JUnit failure mismatch: %+v", failure)
+	}
+}
+
+func TestUnknownReportFormat(t *testing.T) {
+	if _, err := GetReporter("yaml"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	warnings := []rules.Diagnostic{{Severity: rules.SeverityInfo}, {Severity: rules.SeverityWarning}, {Severity: rules.SeverityWarning}}
+
+	tests := []struct {
+		name        string
+		thresholds  Thresholds
+		diagnostics []rules.Diagnostic
+		expected    bool
+	}{
+		{"no diagnostics", Thresholds{FailOn: rules.SeverityInfo, MaxWarnings: -1}, nil, false},
+		{"fail on warnings", Thresholds{FailOn: rules.SeverityWarning, MaxWarnings: -1}, warnings, true},
+		{"fail on errors", Thresholds{FailOn: rules.SeverityError, MaxWarnings: -1}, warnings, false},
+		{"errors over the warnings", Thresholds{FailOn: rules.SeverityError, MaxWarnings: 5}, reportDiagnostics(), true},
+		{"warnings within the limit", Thresholds{FailOn: rules.SeverityError, MaxWarnings: 2}, warnings, false},
+		{"warnings over the limit", Thresholds{FailOn: rules.SeverityError, MaxWarnings: 1}, warnings, true},
+	}
+
+	for _, test := range tests {
+		if actual := test.thresholds.Exceeded(test.diagnostics); actual != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestParseFailureReport(t *testing.T) {
+	diagnostics, err := CheckSource([]byte("locals {\n  a = \n}\n"), "main.tf", DefaultConfig(t.TempDir()))
+	if err == nil {
+		t.Fatalf("Expected an error for content that doesn't parse")
+	}
+
+	reporter, err := GetReporter("json")
+	if err != nil {
+		t.Fatalf("Failed to get reporter: %v", err)
+	}
+	var out bytes.Buffer
+	if err := reporter(&out, diagnostics); err != nil {
+		t.Fatalf("Failed to write json report: %v", err)
+	}
+	var results []jsonDiagnostic
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected one result, got %+v", results)
+	}
+	if result := results[0]; result.Rule != "parse" || result.Severity != "error" || result.File != "main.tf" || result.Line != 2 || result.Column != 7 || result.Fixable {
+		t.Errorf("JSON result mismatch: %+v", result)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(report(t, "sarif"), &log); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+	found := false
+	for _, rule := range log.Runs[0].Tool.Driver.Rules {
+		found = found || rule.ID == "parse"
+	}
+	if !found {
+		t.Errorf("SARIF rules should describe the parse rule")
+	}
+}
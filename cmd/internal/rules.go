@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/vahid-haghighat/terralint/parser"
+	"github.com/vahid-haghighat/terralint/rules"
+)
+
+func init() {
+	rules.Register(formatRule{})
+	rules.Register(orderRule{})
+	rules.Register(undefinedReferenceRule{})
+	rules.Register(unusedDeclarationRule{})
+	rules.Register(variableDescriptionRule{})
+	rules.Register(variableTypeRule{})
+	rules.Register(variableDefaultRule{})
+	rules.Register(outputDescriptionRule{})
+	rules.Register(outputSensitiveRule{})
+	rules.Register(labelNamingRule{})
+	rules.Register(attributeNamingRule{})
+	rules.Register(redundantResourceNameRule{})
+}
+
+// formatRule reports the lines that differ from the printed form of a file.
+// The items out of order stay where they are, the order rule reports them.
+type formatRule struct {
+	config *Config
+}
+
+func (r formatRule) withConfig(config *Config) rules.Rule {
+	return formatRule{config: config}
+}
+
+func (formatRule) ID() string {
+	return "format"
+}
+
+func (formatRule) Severity() rules.Severity {
+	return rules.SeverityWarning
+}
+
+func (formatRule) Description() string {
+	return "Files are formatted and ordered in the canonical style"
+}
+
+func (r formatRule) Check(file *rules.File) []rules.Diagnostic {
+	formatted, err := formatSource(file.Content, file.Path, r.config, false)
+	if err != nil {
+		return nil
+	}
+
+	var diagnostics []rules.Diagnostic
+	for _, hunk := range diffHunks(file.Path, string(file.Content), string(formatted)) {
+		diagnostics = append(diagnostics, rules.Diagnostic{
+			Range:   hunk.Range,
+			Message: "not formatted according to the canonical style",
+			Fix:     &rules.Fix{Edits: []rules.Edit{hunk}},
+		})
+	}
+	return diagnostics
+}
+
+// orderRule reports the items that are not where their priority lists place
+// them. Formatting a file moves them, the rule has no fix of its own.
+type orderRule struct {
+	config *Config
+}
+
+func (r orderRule) withConfig(config *Config) rules.Rule {
+	return orderRule{config: config}
+}
+
+func (orderRule) ID() string {
+	return "order"
+}
+
+func (orderRule) Severity() rules.Severity {
+	return rules.SeverityError
+}
+
+func (orderRule) Description() string {
+	return "Attributes and blocks follow the order of the priority lists"
+}
+
+func (r orderRule) Check(file *rules.File) []rules.Diagnostic {
+	root, err := parser.ParseSource(file.Content, file.Path)
+	if err != nil {
+		return nil
+	}
+
+	var diagnostics []rules.Diagnostic
+	for _, violation := range reorderRoot(root, file.Path, r.config, false).violations {
+		diagnostics = append(diagnostics, rules.Diagnostic{
+			Range:   itemRange(violation.item),
+			Message: violation.message,
+		})
+	}
+	return diagnostics
+}
+
+// diffHunks returns the line based differences between original and formatted
+// as edits of the original
+func diffHunks(filename, original, formatted string) []rules.Edit {
+	var hunks []rules.Edit
+	var hunk *rules.Edit
+	pos := hcl.InitialPos
+	for _, diff := range lineDiff(original, formatted) {
+		switch diff.Type {
+		case diffmatchpatch.DiffEqual:
+			if hunk != nil {
+				hunk.Range.End = pos
+				hunks = append(hunks, *hunk)
+				hunk = nil
+			}
+			pos = advance(pos, diff.Text)
+			continue
+		}
+
+		if hunk == nil {
+			hunk = &rules.Edit{Range: hcl.Range{Filename: filename, Start: pos}}
+		}
+		if diff.Type == diffmatchpatch.DiffDelete {
+			pos = advance(pos, diff.Text)
+		} else {
+			hunk.NewText += diff.Text
+		}
+	}
+	if hunk != nil {
+		hunk.Range.End = pos
+		hunks = append(hunks, *hunk)
+	}
+	return hunks
+}
+
+// lineDiff compares two texts line by line. Every distinct line is encoded as
+// a single rune, as the line mode of diffmatchpatch splits the numbers it
+// encodes lines with.
+func lineDiff(original, formatted string) []diffmatchpatch.Diff {
+	var lines []string
+	codes := make(map[string]rune)
+	encode := func(text string) []rune {
+		var encoded []rune
+		for len(text) > 0 {
+			end := strings.IndexByte(text, '\n') + 1
+			if end == 0 {
+				end = len(text)
+			}
+			line := text[:end]
+			text = text[end:]
+
+			code, found := codes[line]
+			if !found {
+				// Skip the surrogate halves, they aren't valid runes
+				code = rune(len(lines) + 1)
+				if code >= 0xD800 {
+					code += 0x800
+				}
+				codes[line] = code
+				lines = append(lines, line)
+			}
+			encoded = append(encoded, code)
+		}
+		return encoded
+	}
+	a, b := encode(original), encode(formatted)
+
+	diffs := diffmatchpatch.New().DiffMainRunes(a, b, false)
+	for i, diff := range diffs {
+		var text strings.Builder
+		for _, code := range diff.Text {
+			if code >= 0xE000 {
+				code -= 0x800
+			}
+			text.WriteString(lines[code-1])
+		}
+		diffs[i].Text = text.String()
+	}
+	return diffs
+}
+
+// advance moves pos past text
+func advance(pos hcl.Pos, text string) hcl.Pos {
+	pos.Byte += len(text)
+	if newline := strings.LastIndexByte(text, '\n'); newline >= 0 {
+		pos.Line += strings.Count(text, "\n")
+		pos.Column = utf8.RuneCountInString(text[newline+1:]) + 1
+	} else {
+		pos.Column += utf8.RuneCountInString(text)
+	}
+	return pos
+}
@@ -0,0 +1,480 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/vahid-haghighat/terralint/parser/types"
+	"github.com/vahid-haghighat/terralint/rules"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// topLevelBlocks returns the blocks of a type at the top level of a file that
+// have the single label they are named with
+func topLevelBlocks(root *types.Root, blockType string) []*types.Block {
+	var blocks []*types.Block
+	for _, child := range root.Children {
+		if block, ok := child.(*types.Block); ok && block.Type == blockType && len(block.Labels) == 1 {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// blockAttribute returns the attribute of a block with the name, or nil
+func blockAttribute(block *types.Block, name string) *types.Attribute {
+	for _, child := range block.Children {
+		if attribute, ok := child.(*types.Attribute); ok && attribute.Name == name {
+			return attribute
+		}
+	}
+	return nil
+}
+
+// isTrue reports whether an attribute is set to the literal true
+func isTrue(attribute *types.Attribute) bool {
+	if attribute == nil {
+		return false
+	}
+	literal, ok := attribute.Value.(*types.LiteralValue)
+	return ok && literal.Value == true
+}
+
+// missingDescription returns the diagnostics of the blocks of a type that have
+// no description, or an empty one
+func missingDescription(file *rules.File, blockType string) []rules.Diagnostic {
+	var diagnostics []rules.Diagnostic
+	for _, block := range topLevelBlocks(file.Root, blockType) {
+		description := blockAttribute(block, "description")
+		if description == nil {
+			diagnostics = append(diagnostics, rules.Diagnostic{
+				Range:   block.Range,
+				Message: fmt.Sprintf("%s %q has no description", blockType, block.Labels[0]),
+			})
+			continue
+		}
+		if literal, ok := description.Value.(*types.LiteralValue); ok && literal.Value == "" {
+			diagnostics = append(diagnostics, rules.Diagnostic{
+				Range:   description.Range,
+				Message: fmt.Sprintf("%s %q has an empty description", blockType, block.Labels[0]),
+			})
+		}
+	}
+	return diagnostics
+}
+
+// variableDescriptionRule reports the variables without a description
+type variableDescriptionRule struct{}
+
+func (variableDescriptionRule) ID() string {
+	return "variable-description"
+}
+
+func (variableDescriptionRule) Severity() rules.Severity {
+	return rules.SeverityWarning
+}
+
+func (variableDescriptionRule) Description() string {
+	return "Variables have a description"
+}
+
+func (variableDescriptionRule) Check(file *rules.File) []rules.Diagnostic {
+	return missingDescription(file, types.KindVariable)
+}
+
+// variableTypeRule reports the variables without an explicit type
+type variableTypeRule struct{}
+
+func (variableTypeRule) ID() string {
+	return "variable-type"
+}
+
+func (variableTypeRule) Severity() rules.Severity {
+	return rules.SeverityWarning
+}
+
+func (variableTypeRule) Description() string {
+	return "Variables have an explicit type"
+}
+
+func (variableTypeRule) Check(file *rules.File) []rules.Diagnostic {
+	var diagnostics []rules.Diagnostic
+	for _, block := range topLevelBlocks(file.Root, types.KindVariable) {
+		if blockAttribute(block, "type") == nil {
+			diagnostics = append(diagnostics, rules.Diagnostic{
+				Range:   block.Range,
+				Message: fmt.Sprintf("variable %q has no type", block.Labels[0]),
+			})
+		}
+	}
+	return diagnostics
+}
+
+// variableDefaultRule reports the defaults that can't be converted to the type
+// of their variable. Defaults that aren't made of literals only, and types that
+// aren't valid, are left to Terraform.
+type variableDefaultRule struct{}
+
+func (variableDefaultRule) ID() string {
+	return "variable-default"
+}
+
+func (variableDefaultRule) Severity() rules.Severity {
+	return rules.SeverityError
+}
+
+func (variableDefaultRule) Description() string {
+	return "The defaults of variables match their type"
+}
+
+func (variableDefaultRule) Check(file *rules.File) []rules.Diagnostic {
+	var diagnostics []rules.Diagnostic
+	for _, block := range topLevelBlocks(file.Root, types.KindVariable) {
+		typeAttribute, defaultAttribute := blockAttribute(block, "type"), blockAttribute(block, "default")
+		if typeAttribute == nil || defaultAttribute == nil {
+			continue
+		}
+		constraint, err := typeConstraint(typeAttribute.Value)
+		if err != nil {
+			continue
+		}
+		value, ok := constantValue(defaultAttribute.Value)
+		if !ok {
+			continue
+		}
+
+		if _, err := convert.Convert(value, constraint); err != nil {
+			diagnostics = append(diagnostics, rules.Diagnostic{
+				Range:   defaultAttribute.Value.Range(),
+				Message: fmt.Sprintf("the default of variable %q doesn't match its type: %s", block.Labels[0], err),
+			})
+		}
+	}
+	return diagnostics
+}
+
+// typeConstraint evaluates a type constraint like list(object({ name = string }))
+func typeConstraint(expr types.Expression) (cty.Type, error) {
+	switch e := expr.(type) {
+	case *types.ReferenceExpr:
+		if len(e.Parts) == 1 {
+			switch e.Parts[0] {
+			case "string":
+				return cty.String, nil
+			case "number":
+				return cty.Number, nil
+			case "bool":
+				return cty.Bool, nil
+			case "any":
+				return cty.DynamicPseudoType, nil
+			}
+		}
+	case *types.FunctionCallExpr:
+		if len(e.Args) != 1 {
+			break
+		}
+		switch e.Name {
+		case "list", "set", "map":
+			element, err := typeConstraint(e.Args[0])
+			if err != nil {
+				return cty.NilType, err
+			}
+			switch e.Name {
+			case "list":
+				return cty.List(element), nil
+			case "set":
+				return cty.Set(element), nil
+			}
+			return cty.Map(element), nil
+		case "tuple":
+			elements, ok := e.Args[0].(*types.ArrayExpr)
+			if !ok {
+				break
+			}
+			var elementTypes []cty.Type
+			for _, item := range elements.Items {
+				element, err := typeConstraint(item)
+				if err != nil {
+					return cty.NilType, err
+				}
+				elementTypes = append(elementTypes, element)
+			}
+			return cty.Tuple(elementTypes), nil
+		case "object":
+			return objectConstraint(e.Args[0])
+		}
+	}
+	return cty.NilType, fmt.Errorf("invalid type constraint")
+}
+
+// objectConstraint evaluates the attributes of an object type constraint, which
+// can be marked optional
+func objectConstraint(expr types.Expression) (cty.Type, error) {
+	object, ok := expr.(*types.ObjectExpr)
+	if !ok {
+		return cty.NilType, fmt.Errorf("invalid object type constraint")
+	}
+
+	attributes := make(map[string]cty.Type)
+	var optional []string
+	for _, item := range object.Items {
+		name, ok := objectKey(item.Key)
+		if !ok {
+			return cty.NilType, fmt.Errorf("invalid object attribute")
+		}
+		value := item.Value
+		if call, ok := value.(*types.FunctionCallExpr); ok && call.Name == "optional" && len(call.Args) > 0 {
+			optional = append(optional, name)
+			value = call.Args[0]
+		}
+		attribute, err := typeConstraint(value)
+		if err != nil {
+			return cty.NilType, err
+		}
+		attributes[name] = attribute
+	}
+	return cty.ObjectWithOptionalAttrs(attributes, optional), nil
+}
+
+// objectKey returns the name an object key spells, which is either a bare name
+// or a literal string
+func objectKey(key types.Expression) (string, bool) {
+	switch k := key.(type) {
+	case *types.ReferenceExpr:
+		if len(k.Parts) == 1 {
+			return k.Parts[0], true
+		}
+	case *types.LiteralValue:
+		if name, ok := k.Value.(string); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// constantValue evaluates an expression made of literals only, and reports
+// false for any other expression
+func constantValue(expr types.Expression) (cty.Value, bool) {
+	switch e := expr.(type) {
+	case *types.LiteralValue:
+		switch value := e.Value.(type) {
+		case nil:
+			return cty.NullVal(cty.DynamicPseudoType), true
+		case string:
+			return cty.StringVal(value), true
+		case bool:
+			return cty.BoolVal(value), true
+		case int64:
+			return cty.NumberIntVal(value), true
+		case float64:
+			return cty.NumberFloatVal(value), true
+		}
+	case *types.ParenExpr:
+		return constantValue(e.Expression)
+	case *types.UnaryExpr:
+		if value, ok := constantValue(e.Expr); ok && e.Operator == "-" && value.Type() == cty.Number {
+			return value.Negate(), true
+		}
+	case *types.TemplateExpr:
+		var text strings.Builder
+		for _, part := range e.Parts {
+			literal, ok := part.(*types.LiteralValue)
+			if !ok {
+				return cty.NilVal, false
+			}
+			fmt.Fprint(&text, literal.Value)
+		}
+		return cty.StringVal(text.String()), true
+	case *types.ArrayExpr:
+		return constantTuple(e.Items)
+	case *types.TupleExpr:
+		return constantTuple(e.Expressions)
+	case *types.ObjectExpr:
+		attributes := make(map[string]cty.Value)
+		for _, item := range e.Items {
+			name, ok := objectKey(item.Key)
+			if !ok {
+				return cty.NilVal, false
+			}
+			value, ok := constantValue(item.Value)
+			if !ok {
+				return cty.NilVal, false
+			}
+			attributes[name] = value
+		}
+		return cty.ObjectVal(attributes), true
+	}
+	return cty.NilVal, false
+}
+
+func constantTuple(items []types.Expression) (cty.Value, bool) {
+	if len(items) == 0 {
+		return cty.EmptyTupleVal, true
+	}
+	var values []cty.Value
+	for _, item := range items {
+		value, ok := constantValue(item)
+		if !ok {
+			return cty.NilVal, false
+		}
+		values = append(values, value)
+	}
+	return cty.TupleVal(values), true
+}
+
+// outputDescriptionRule reports the outputs without a description
+type outputDescriptionRule struct{}
+
+func (outputDescriptionRule) ID() string {
+	return "output-description"
+}
+
+func (outputDescriptionRule) Severity() rules.Severity {
+	return rules.SeverityWarning
+}
+
+func (outputDescriptionRule) Description() string {
+	return "Outputs have a description"
+}
+
+func (outputDescriptionRule) Check(file *rules.File) []rules.Diagnostic {
+	return missingDescription(file, types.KindOutput)
+}
+
+// outputSensitiveRule reports the outputs that expose sensitive variables,
+// directly or through locals, without being sensitive themselves. The fix marks
+// them sensitive. What a nonsensitive call wraps isn't exposed.
+type outputSensitiveRule struct{}
+
+func (outputSensitiveRule) ID() string {
+	return "output-sensitive"
+}
+
+func (outputSensitiveRule) Severity() rules.Severity {
+	return rules.SeverityError
+}
+
+func (outputSensitiveRule) Description() string {
+	return "Outputs exposing sensitive variables are sensitive"
+}
+
+func (outputSensitiveRule) Check(file *rules.File) []rules.Diagnostic {
+	if file.Module == nil {
+		return nil
+	}
+
+	exposed := sensitiveDeclarations(file.Module)
+	var diagnostics []rules.Diagnostic
+	for _, output := range file.Module.Outputs {
+		value := blockAttribute(output.Block, "value")
+		if output.File != file.Path || value == nil || isTrue(blockAttribute(output.Block, "sensitive")) {
+			continue
+		}
+
+		unwrapped := nonsensitiveCallsOf(value)
+		for _, binding := range file.Module.Resolve() {
+			variable := exposed[binding.Declaration]
+			offset := binding.Reference.Range().Start.Byte
+			if variable == nil || binding.File != output.File || !value.Range.ContainsOffset(offset) || unwrapped.contains(offset) {
+				continue
+			}
+			diagnostic := rules.Diagnostic{
+				Range:   output.Range,
+				Message: fmt.Sprintf("output %q exposes the sensitive variable %q and should be sensitive", output.Name, variable.Name),
+			}
+			if edit, ok := markSensitive(file.Content, output.Block); ok {
+				diagnostic.Fix = &rules.Fix{Edits: []rules.Edit{edit}}
+			}
+			diagnostics = append(diagnostics, diagnostic)
+			break
+		}
+	}
+	return diagnostics
+}
+
+// sensitiveDeclarations returns the sensitive variable every sensitive variable
+// and every local referring to one exposes
+func sensitiveDeclarations(module *types.Module) map[*types.Declaration]*types.Declaration {
+	exposed := make(map[*types.Declaration]*types.Declaration)
+	for _, variable := range module.Variables {
+		if isTrue(blockAttribute(variable.Block, "sensitive")) {
+			exposed[variable] = variable
+		}
+	}
+
+	unwrapped := make(map[*types.Declaration]nonsensitiveCalls, len(module.Locals))
+	for _, local := range module.Locals {
+		unwrapped[local] = nonsensitiveCallsOf(local.Attribute)
+	}
+
+	// Locals can refer to each other in any order
+	for changed := true; changed; {
+		changed = false
+		for _, binding := range module.Resolve() {
+			variable := exposed[binding.Declaration]
+			if variable == nil {
+				continue
+			}
+			offset := binding.Reference.Range().Start.Byte
+			for _, local := range module.Locals {
+				if exposed[local] == nil && local.File == binding.File && local.Range.ContainsOffset(offset) && !unwrapped[local].contains(offset) {
+					exposed[local] = variable
+					changed = true
+				}
+			}
+		}
+	}
+	return exposed
+}
+
+// nonsensitiveCalls is the visitor collecting the ranges of the nonsensitive
+// calls of an expression, whose result isn't sensitive whatever they wrap
+type nonsensitiveCalls []hcl.Range
+
+func nonsensitiveCallsOf(attribute *types.Attribute) nonsensitiveCalls {
+	var calls nonsensitiveCalls
+	if attribute != nil && attribute.Value != nil {
+		types.Walk(&calls, attribute.Value)
+	}
+	return calls
+}
+
+func (c *nonsensitiveCalls) Visit(node types.Node) types.Visitor {
+	if call, ok := node.(*types.FunctionCallExpr); ok && call.Name == "nonsensitive" {
+		*c = append(*c, call.ExprRange)
+		return nil
+	}
+	return c
+}
+
+// contains reports whether one of the calls holds the byte offset
+func (c nonsensitiveCalls) contains(offset int) bool {
+	for _, rng := range c {
+		if rng.ContainsOffset(offset) {
+			return true
+		}
+	}
+	return false
+}
+
+// markSensitive returns the edit setting sensitive to true in a block, either
+// by replacing its value or by adding it before the closing brace. A block that
+// ends on a line with other content isn't edited.
+func markSensitive(content []byte, block *types.Block) (rules.Edit, bool) {
+	if sensitive := blockAttribute(block, "sensitive"); sensitive != nil {
+		return rules.Edit{Range: sensitive.Value.Range(), NewText: "true"}, true
+	}
+
+	end := block.Range.End
+	lineStart := end.Byte - 1
+	for lineStart > 0 && content[lineStart-1] != '\n' {
+		lineStart--
+		if content[lineStart] != ' ' && content[lineStart] != '\t' {
+			return rules.Edit{}, false
+		}
+	}
+	start := hcl.Pos{Line: end.Line, Column: 1, Byte: lineStart}
+	return rules.Edit{Range: hcl.Range{Filename: block.Range.Filename, Start: start, End: start}, NewText: "  sensitive = true\n"}, true
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var hygieneRules = map[string]bool{
+	"variable-description": true,
+	"variable-type":        true,
+	"variable-default":     true,
+	"output-description":   true,
+	"output-sensitive":     true,
+}
+
+func TestVariableRules(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"variables.tf": `variable "untyped" {
+  description = "Has no type"
+}
+
+variable "undescribed" {
+  type = string
+}
+
+variable "empty" {
+  description = ""
+  type        = number
+  default     = 1
+}
+
+variable "port" {
+  description = "Not a number"
+  type        = number
+  default     = "http"
+}
+
+variable "servers" {
+  description = "Missing a required attribute"
+  type = list(object({
+    name = string
+    port = optional(number, 80)
+  }))
+  default = [{ name = "a" }, { port = 8080 }]
+}
+
+variable "tags" {
+  description = "Fine"
+  type        = map(string)
+  default     = { env = "prod", tier = 1 }
+}
+
+variable "password" {
+  description = "Secret"
+  type        = string
+  sensitive   = true
+}
+`,
+		"outputs.tf": `locals {
+  credentials = "admin:${var.password}"
+}
+
+output "credentials" {
+  description = "Exposed through a local"
+  value       = local.credentials
+}
+
+output "password" {
+  description = "Marked sensitive"
+  value       = var.password
+  sensitive   = true
+}
+
+output "tags" {
+  value = var.tags
+}
+
+output "port" {
+  description = "Explicitly not sensitive"
+  value       = { port = var.port, password = var.password }
+  sensitive   = false
+}
+
+locals {
+  hashed = nonsensitive(sha256(var.password))
+}
+
+output "hashed" {
+  description = "Lifted by a local"
+  value       = local.hashed
+}
+
+output "length" {
+  description = "Lifted in the value"
+  value       = nonsensitive(length(var.password))
+}
+`,
+	}
+	writeFiles(t, root, files)
+
+	targets := []Target{{Path: root, Config: DefaultConfig(root)}}
+	diagnostics, err := Check(targets, 2)
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+	var messages []string
+	for _, diagnostic := range diagnostics {
+		if hygieneRules[diagnostic.Rule] {
+			messages = append(messages, fmt.Sprintf("%s:%d %s: %s", filepath.Base(diagnostic.File), diagnostic.Range.Start.Line, diagnostic.Rule, diagnostic.Message))
+		}
+	}
+	expected := []string{
+		`outputs.tf:5 output-sensitive: output "credentials" exposes the sensitive variable "password" and should be sensitive`,
+		`outputs.tf:16 output-description: output "tags" has no description`,
+		`outputs.tf:20 output-sensitive: output "port" exposes the sensitive variable "password" and should be sensitive`,
+		`variables.tf:1 variable-type: variable "untyped" has no type`,
+		`variables.tf:5 variable-description: variable "undescribed" has no description`,
+		`variables.tf:10 variable-description: variable "empty" has an empty description`,
+		`variables.tf:18 variable-default: the default of variable "port" doesn't match its type: a number is required`,
+		`variables.tf:27 variable-default: the default of variable "servers" doesn't match its type: element 1: attribute "name" is required`,
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Diagnostics mismatch:\nexpected: %v\ngot:      %v", expected, messages)
+	}
+
+	if _, err := Apply(targets, 2, ApplyOptions{Fix: []string{"output-sensitive"}}); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+	diagnostics, err = Check(targets, 2)
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Rule == "output-sensitive" {
+			actual, _ := os.ReadFile(filepath.Join(root, "outputs.tf"))
+			t.Errorf("Unexpected diagnostic after the fix: %v\n%s", diagnostic, actual)
+		}
+	}
+}
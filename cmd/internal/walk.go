@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// fileResult is the outcome of processing one file of a tree
+type fileResult[T any] struct {
+	path  string
+	value T
+	err   error
+}
+
+// Target is a file or directory to process with the config that applies to it
+type Target struct {
+	Path   string
+	Config *Config
+}
+
+// job is a file to process
+type job struct {
+	path   string
+	config *Config
+}
+
+// processTrees walks the targets once and hands every file their configs
+// include to process, running at most jobs of them at a time. A file reached
+// from several targets, or through symbolic links, is processed once. The
+// skipped directories and the paths of ignore files are left out. The results
+// are sorted by path.
+func processTrees[T any](targets []Target, jobs int, process func(path string, config *Config) (T, error)) ([]fileResult[T], error) {
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+
+	queue := make(chan job)
+	results := make(chan fileResult[T])
+
+	var workers sync.WaitGroup
+	for range jobs {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for j := range queue {
+				value, err := process(j.path, j.config)
+				results <- fileResult[T]{path: j.path, value: value, err: err}
+			}
+		}()
+	}
+
+	walked := make(chan error, 1)
+	go func() {
+		defer close(queue)
+		seen := make(map[string]bool)
+		for _, target := range targets {
+			if err := walkTarget(target, seen, queue); err != nil {
+				walked <- err
+				return
+			}
+		}
+		walked <- nil
+	}()
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var collected []fileResult[T]
+	for result := range results {
+		collected = append(collected, result)
+	}
+	sort.Slice(collected, func(i, j int) bool {
+		return collected[i].path < collected[j].path
+	})
+
+	if err := <-walked; err != nil {
+		return nil, err
+	}
+	return collected, nil
+}
+
+// walkTarget queues the files of a target that weren't seen yet
+func walkTarget(target Target, seen map[string]bool, queue chan<- job) error {
+	root, err := filepath.Abs(target.Path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	ig, err := newIgnorer(root, target.Config)
+	if err != nil {
+		return err
+	}
+	if ig.excluded(root, info.IsDir()) {
+		return nil
+	}
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// The root was checked before walking
+		if path != root && ig.ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !target.Config.Includes(path) {
+			return nil
+		}
+		// A file linked to from several places is processed once, so that it
+		// isn't written by two workers at the same time
+		key, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			key = path
+		}
+		if !seen[key] {
+			seen[key] = true
+			queue <- job{path: path, config: target.Config}
+		}
+		return nil
+	})
+}
+
+// resultErrors joins the errors of the files in path order
+func resultErrors[T any](results []fileResult[T]) error {
+	var errs []error
+	for _, result := range results {
+		if result.err != nil {
+			errs = append(errs, result.err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestProcessTree(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.tf":                           "",
+		"modules/network/main.tf":           "",
+		"modules/network/variables.tf":      "",
+		"modules/network/nested/outputs.tf": "",
+		"prod.tfvars":                       "",
+		"README.md":                         "",
+	})
+
+	var mu sync.Mutex
+	visits := make(map[string]int)
+	results, err := processTrees([]Target{{Path: root, Config: DefaultConfig(root)}}, 3, func(path string, _ *Config) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		visits[path]++
+		return filepath.Base(path), nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to process tree: %v", err)
+	}
+
+	expected := []string{
+		"main.tf",
+		"modules/network/main.tf",
+		"modules/network/nested/outputs.tf",
+		"modules/network/variables.tf",
+		"prod.tfvars",
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, result := range results {
+		if want := filepath.Join(root, filepath.FromSlash(expected[i])); result.path != want {
+			t.Errorf("Result %d: expected %s, got %s", i, want, result.path)
+		}
+		if result.value != filepath.Base(result.path) {
+			t.Errorf("Result %d holds the value of another file: %s", i, result.value)
+		}
+		if visits[result.path] != 1 {
+			t.Errorf("%s was processed %d times", result.path, visits[result.path])
+		}
+	}
+
+	if _, err := processTrees([]Target{{Path: filepath.Join(root, "missing"), Config: DefaultConfig(root)}}, 3, func(string, *Config) (string, error) {
+		return "", nil
+	}); err == nil {
+		t.Errorf("Expected an error for a missing root")
+	}
+
+	// A file reached from several targets is processed once
+	visits = make(map[string]int)
+	config := DefaultConfig(root)
+	results, err = processTrees([]Target{
+		{Path: filepath.Join(root, "modules", "network", "main.tf"), Config: config},
+		{Path: root, Config: config},
+		{Path: filepath.Join(root, "modules"), Config: config},
+	}, 3, func(path string, _ *Config) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		visits[path]++
+		return "", nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to process targets: %v", err)
+	}
+	if len(results) != len(expected) {
+		t.Errorf("Expected %d results for overlapping targets, got %d", len(expected), len(results))
+	}
+	for path, count := range visits {
+		if count != 1 {
+			t.Errorf("%s was processed %d times", path, count)
+		}
+	}
+}
@@ -4,14 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/vahid-haghighat/terralint/cmd/internal"
 	"github.com/vahid-haghighat/terralint/cmd/utilities"
 	"github.com/vahid-haghighat/terralint/version"
+	"io"
 	"os"
+	"runtime"
 )
 
-var terraformPath string
+var terraformPaths []string
 var terraformFilePath string
 var terraformDirectoryPath string
+var configPath string
+var jobs int
+var respectGitignore bool
 var versionFlag bool
 
 // rootCmd represents the base command when called without any subcommands
@@ -19,6 +25,12 @@ var rootCmd = &cobra.Command{
 	Use:   "terralint",
 	Short: "Terraform Linter",
 	Long:  `Checks and lint terraform files based on an opinionated style guide.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 {
+			return usageError(fmt.Errorf("unknown command %q for %q", args[0], cmd.CommandPath()))
+		}
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if versionFlag {
 			fmt.Println(version.Version)
@@ -29,49 +41,125 @@ var rootCmd = &cobra.Command{
 	},
 }
 
-func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
-	}
-}
-
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&terraformFilePath, "file", "f", "", "The path to a terraform file.")
 	rootCmd.PersistentFlags().StringVarP(&terraformDirectoryPath, "directory", "d", "", "The path to the root of a terraform repository.")
-	rootCmd.MarkFlagsMutuallyExclusive("file", "directory")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "The path to a .terralint.hcl config file, overriding the one found from the target.")
+	rootCmd.PersistentFlags().BoolVar(&respectGitignore, "gitignore", false, "Skip the paths listed in .gitignore files too.")
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "The number of files processed at the same time.")
 
 	rootCmd.Flags().BoolVarP(&versionFlag, "version", "v", false, "Print the version number of terralint.")
 }
 
+// stdinPath is the path that stands for the standard input
+const stdinPath = "-"
+
+// readsStdin reports whether the content comes from the standard input
+func readsStdin() bool {
+	return len(terraformPaths) == 1 && terraformPaths[0] == stdinPath
+}
+
+// resolveConfig returns the config of a target with the command line flags applied
+func resolveConfig(target string) (*internal.Config, error) {
+	config, err := internal.ResolveConfig(target, configPath)
+	if err != nil {
+		return nil, usageError(err)
+	}
+	if respectGitignore {
+		config.RespectGitignore = true
+	}
+	return config, nil
+}
+
+// readStdin reads the standard input and the config found from the working directory
+func readStdin(cmd *cobra.Command) ([]byte, *internal.Config, error) {
+	content, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := resolveConfig(".")
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, config, nil
+}
+
+// resolveTargets returns the paths to process with the config found for each
+func resolveTargets() ([]internal.Target, error) {
+	targets := make([]internal.Target, 0, len(terraformPaths))
+	for _, path := range terraformPaths {
+		config, err := resolveConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, internal.Target{Path: path, Config: config})
+	}
+	return targets, nil
+}
+
+// validateArgs checks the flags and the paths of a command before it runs
 func validateArgs(cmd *cobra.Command, args []string) error {
-	if terraformFilePath == "" && terraformDirectoryPath == "" {
-		return errors.New("exactly one of the command flags should be set")
+	if err := cmd.ValidateFlagGroups(); err != nil {
+		return usageError(err)
 	}
+	return usageError(collectPaths(args))
+}
 
-	if terraformFilePath != "" {
-		terraformPath, _ = utilities.AbsPath(terraformFilePath)
-		fileInfo, err := os.Stat(terraformPath)
+// collectPaths collects the paths given as arguments and with the file and
+// directory flags, which must exist. The paths that don't are input errors
+// rather than usage errors.
+func collectPaths(args []string) error {
+	terraformPaths = nil
+
+	for _, arg := range args {
+		if arg == stdinPath {
+			if len(args) > 1 || terraformFilePath != "" || terraformDirectoryPath != "" {
+				return errors.New("- reads from the standard input and can't be combined with other paths")
+			}
+			terraformPaths = []string{stdinPath}
+			return nil
+		}
 
+		path, err := utilities.AbsPath(arg)
 		if err != nil {
 			return err
 		}
+		if _, err := os.Stat(path); err != nil {
+			return inputError(err)
+		}
+		terraformPaths = append(terraformPaths, path)
+	}
+
+	if terraformFilePath != "" {
+		path, _ := utilities.AbsPath(terraformFilePath)
+		fileInfo, err := os.Stat(path)
+
+		if err != nil {
+			return inputError(err)
+		}
 
 		if fileInfo.IsDir() {
 			return errors.New("expected a file path, received a directory path")
 		}
-
-		return nil
+		terraformPaths = append(terraformPaths, path)
 	}
-	terraformPath, _ = utilities.AbsPath(terraformDirectoryPath)
-	fileInfo, err := os.Stat(terraformPath)
 
-	if err != nil {
-		return err
+	if terraformDirectoryPath != "" {
+		path, _ := utilities.AbsPath(terraformDirectoryPath)
+		fileInfo, err := os.Stat(path)
+
+		if err != nil {
+			return inputError(err)
+		}
+
+		if !fileInfo.IsDir() {
+			return errors.New("expected a directory path, received a file path")
+		}
+		terraformPaths = append(terraformPaths, path)
 	}
 
-	if !fileInfo.IsDir() {
-		return errors.New("expected a directory path, received a file path")
+	if len(terraformPaths) == 0 {
+		return errors.New("expected at least one file or directory path, or - to read from the standard input")
 	}
 	return nil
 }
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollectPaths(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(file, []byte("locals {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.tf")
+
+	tests := []struct {
+		name      string
+		args      []string
+		file      string
+		directory string
+		expected  []string
+		exitCode  int
+	}{
+		{name: "Arguments", args: []string{file, dir}, expected: []string{file, dir}},
+		{name: "Flags", file: file, directory: dir, expected: []string{file, dir}},
+		{name: "Standard input", args: []string{"-"}, expected: []string{"-"}},
+		{name: "Standard input with a path", args: []string{"-", file}, exitCode: ExitUsageError},
+		{name: "Path with the standard input", args: []string{file, "-"}, exitCode: ExitUsageError},
+		{name: "Standard input with a flag", args: []string{"-"}, directory: dir, exitCode: ExitUsageError},
+		{name: "Missing argument", args: []string{missing}, exitCode: ExitFileError},
+		{name: "Missing file flag", file: missing, exitCode: ExitFileError},
+		{name: "Directory as a file", file: dir, exitCode: ExitUsageError},
+		{name: "Nothing", exitCode: ExitUsageError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			terraformFilePath, terraformDirectoryPath = test.file, test.directory
+			defer func() {
+				terraformFilePath, terraformDirectoryPath = "", ""
+			}()
+
+			err := usageError(collectPaths(test.args))
+			if actual := exitCode(err); actual != test.exitCode {
+				t.Fatalf("Expected exit code %d, got %d for error: %v", test.exitCode, actual, err)
+			}
+			if err == nil && !reflect.DeepEqual(terraformPaths, test.expected) {
+				t.Errorf("Paths mismatch:\nexpected: %q\ngot:      %q", test.expected, terraformPaths)
+			}
+		})
+	}
+}
+
+func TestStdinRoundTrip(t *testing.T) {
+	// The config is looked up from the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	input := "output \"a\" {\n  description = \"A\"\n  value=1\n}\n"
+	expected := "output \"a\" {\n  description = \"A\"\n  value       = 1\n}\n"
+
+	var out bytes.Buffer
+	rootCmd.SetArgs([]string{"apply", "-"})
+	rootCmd.SetIn(strings.NewReader(input))
+	rootCmd.SetOut(&out)
+	defer func() {
+		rootCmd.SetIn(nil)
+		rootCmd.SetOut(nil)
+	}()
+	if _, err := rootCmd.ExecuteC(); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+	if out.String() != expected {
+		t.Fatalf("Formatted output mismatch:\nexpected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	// The formatted output passes the check
+	rootCmd.SetArgs([]string{"check", "-"})
+	rootCmd.SetIn(strings.NewReader(out.String()))
+	rootCmd.SetOut(io.Discard)
+	if _, err := rootCmd.ExecuteC(); exitCode(err) != ExitClean {
+		t.Errorf("Expected the formatted output to pass the check, got: %v", err)
+	}
+}
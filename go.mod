@@ -7,6 +7,7 @@ require (
 	github.com/sergi/go-diff v1.3.1
 	github.com/smacker/go-tree-sitter v0.0.0-20240827094217-dd81d9e9be82
 	github.com/spf13/cobra v1.9.1
+	github.com/spf13/pflag v1.0.6
 	github.com/zclconf/go-cty v1.16.2
 )
 
@@ -16,6 +17,5 @@ require (
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mitchellh/go-wordwrap v1.0.1 // indirect
-	github.com/spf13/pflag v1.0.6 // indirect
 	golang.org/x/text v0.22.0 // indirect
 )
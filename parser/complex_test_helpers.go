@@ -9,9 +9,9 @@ func createComplexModuleExpected() types.Body {
 	return &types.Root{
 		Children: []types.Body{
 			&types.Block{
-				Type:         "module",
-				Labels:       []string{"complex_module"},
-				BlockComment: "Complex module with nested expressions, conditionals, and for loops",
+				Type:     "module",
+				Labels:   []string{"complex_module"},
+				Comments: types.Comments{Leading: comments("// Complex module with nested expressions, conditionals, and for loops")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name: "source",
@@ -28,8 +28,8 @@ func createComplexModuleExpected() types.Body {
 						},
 					},
 					&types.Attribute{
-						Name:         "vpc_config",
-						BlockComment: "Complex map with nested objects, expressions, and functions",
+						Name:     "vpc_config",
+						Comments: types.Comments{Leading: comments("// Complex map with nested objects, expressions, and functions")},
 						Value: &types.ObjectExpr{
 							Items: []types.ObjectItem{
 								{
@@ -56,8 +56,8 @@ func createComplexModuleExpected() types.Body {
 									},
 								},
 								{
-									Key:          &types.ReferenceExpr{Parts: []string{"enable_dns"}},
-									BlockComment: "Nested conditional expression",
+									Key:      &types.ReferenceExpr{Parts: []string{"enable_dns"}},
+									Comments: types.Comments{Leading: comments("// Nested conditional expression")},
 									Value: &types.ConditionalExpr{
 										Condition: &types.BinaryExpr{
 											Left:     &types.ReferenceExpr{Parts: []string{"var", "environment"}},
@@ -79,8 +79,8 @@ func createComplexModuleExpected() types.Body {
 									},
 								},
 								{
-									Key:          &types.ReferenceExpr{Parts: []string{"tags"}},
-									BlockComment: "Complex object with nested expressions",
+									Key:      &types.ReferenceExpr{Parts: []string{"tags"}},
+									Comments: types.Comments{Leading: comments("// Complex object with nested expressions")},
 									Value: &types.FunctionCallExpr{
 										Name: "merge",
 										Args: []types.Expression{
@@ -165,8 +165,8 @@ func createComplexModuleExpected() types.Body {
 						},
 					},
 					&types.Attribute{
-						Name:         "subnet_cidrs",
-						BlockComment: "Complex for expression with filtering and transformation",
+						Name:     "subnet_cidrs",
+						Comments: types.Comments{Leading: comments("// Complex for expression with filtering and transformation")},
 						Value: &types.ForArrayExpr{
 							KeyVar:     "i",
 							ValueVar:   "subnet",
@@ -191,8 +191,8 @@ func createComplexModuleExpected() types.Body {
 						},
 					},
 					&types.Attribute{
-						Name:         "subnet_configs",
-						BlockComment: "Nested for expressions with conditional",
+						Name:     "subnet_configs",
+						Comments: types.Comments{Leading: comments("// Nested for expressions with conditional")},
 						Value: &types.ForMapExpr{
 							KeyVar:      "zone_key",
 							ValueVar:    "zone",
@@ -288,8 +288,8 @@ func createComplexModuleExpected() types.Body {
 						},
 					},
 					&types.Attribute{
-						Name:         "all_subnet_ids",
-						BlockComment: "Complex splat expression",
+						Name:     "all_subnet_ids",
+						Comments: types.Comments{Leading: comments("// Complex splat expression")},
 						Value: &types.FunctionCallExpr{
 							Name: "flatten",
 							Args: []types.Expression{
@@ -308,17 +308,17 @@ func createComplexModuleExpected() types.Body {
 						},
 					},
 					&types.Attribute{
-						Name:         "user_data",
-						BlockComment: "Heredoc with interpolation",
+						Name:     "user_data",
+						Comments: types.Comments{Leading: comments("// Heredoc with interpolation")},
 						Value: &types.HeredocExpr{
 							Marker:   "EOT",
 							Indented: true,
-							Content:  "#!/bin/bash\necho \"Environment: ${var.environment}\"\necho \"Region: ${data.aws_region.current.name}\"\n\n# Complex interpolation\n${join(\"\\n\", [\n  for script in var.bootstrap_scripts :\n  \"source ${script}\"\n])}\n\n# Conditional section\n${var.install_monitoring ? \"setup_monitoring ${var.monitoring_endpoint}\" : \"echo 'Monitoring disabled'\"}\n\n# For loop in heredoc\n%{for pkg in var.packages~}\nyum install -y ${pkg}\n%{endfor~}\n\n# If directive in heredoc\n%{if var.environment == \"prod\"~}\necho \"Production environment detected, applying strict security\"\n%{else~}\necho \"Non-production environment, using standard security\"\n%{endif~}",
+							Content:  "#!/bin/bash\necho \"Environment: ${var.environment}\"\necho \"Region: ${data.aws_region.current.name}\"\n    \n# Complex interpolation\n${join(\"\\n\", [\n  for script in var.bootstrap_scripts :\n  \"source ${script}\"\n])}\n    \n# Conditional section\n${var.install_monitoring ? \"setup_monitoring ${var.monitoring_endpoint}\" : \"echo 'Monitoring disabled'\"}\n    \n# For loop in heredoc\n%{for pkg in var.packages~}\nyum install -y ${pkg}\n%{endfor~}\n    \n# If directive in heredoc\n%{if var.environment == \"prod\"~}\necho \"Production environment detected, applying strict security\"\n%{else~}\necho \"Non-production environment, using standard security\"\n%{endif~}\n",
 						},
 					},
 					&types.Attribute{
-						Name:         "timeout",
-						BlockComment: "Complex binary expressions with nested conditionals",
+						Name:     "timeout",
+						Comments: types.Comments{Leading: comments("// Complex binary expressions with nested conditionals")},
 						Value: &types.BinaryExpr{
 							Left: &types.ParenExpr{
 								Expression: &types.ConditionalExpr{
@@ -362,8 +362,8 @@ func createComplexModuleExpected() types.Body {
 						},
 					},
 					&types.Attribute{
-						Name:         "complex_calculation",
-						BlockComment: "Nested parentheses and operators",
+						Name:     "complex_calculation",
+						Comments: types.Comments{Leading: comments("// Nested parentheses and operators")},
 						Value: &types.BinaryExpr{
 							Left: &types.ParenExpr{
 								Expression: &types.BinaryExpr{
@@ -433,8 +433,8 @@ func createComplexModuleExpected() types.Body {
 						},
 					},
 					&types.Attribute{
-						Name:         "security_groups",
-						BlockComment: "Complex function calls with nested expressions",
+						Name:     "security_groups",
+						Comments: types.Comments{Leading: comments("// Complex function calls with nested expressions")},
 						Value: &types.FunctionCallExpr{
 							Name: "compact",
 							Args: []types.Expression{
@@ -474,8 +474,8 @@ func createComplexModuleExpected() types.Body {
 						},
 					},
 					&types.Attribute{
-						Name:         "custom_template",
-						BlockComment: "Template with directives",
+						Name:     "custom_template",
+						Comments: types.Comments{Leading: comments("// Template with directives")},
 						Value: &types.FunctionCallExpr{
 							Name: "templatefile",
 							Args: []types.Expression{
@@ -664,11 +664,11 @@ func createComplexModuleExpected() types.Body {
 								},
 							},
 						},
-						BlockComment: "Dynamic blocks with complex expressions",
+						Comments: types.Comments{Leading: comments("// Dynamic blocks with complex expressions")},
 					},
 					&types.Attribute{
-						Name:         "validation",
-						BlockComment: "Complex type constraints",
+						Name:     "validation",
+						Comments: types.Comments{Leading: comments("// Complex type constraints")},
 						Value: &types.ObjectExpr{
 							Items: []types.ObjectItem{
 								{
@@ -704,9 +704,9 @@ func createComplexResourceExpected() types.Body {
 	return &types.Root{
 		Children: []types.Body{
 			&types.Block{
-				Type:         "resource",
-				Labels:       []string{"aws_security_group", "complex"},
-				BlockComment: "Resource with complex dynamic blocks and for_each",
+				Type:     "resource",
+				Labels:   []string{"aws_security_group", "complex"},
+				Comments: types.Comments{Leading: comments("// Resource with complex dynamic blocks and for_each")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name: "for_each",
@@ -814,7 +814,7 @@ func createComplexResourceExpected() types.Body {
 								},
 							},
 						},
-						BlockComment: "Dynamic blocks with nested expressions",
+						Comments: types.Comments{Leading: comments("// Dynamic blocks with nested expressions")},
 					},
 					&types.Block{
 						Type:   "dynamic",
@@ -896,8 +896,8 @@ func createComplexResourceExpected() types.Body {
 						},
 					},
 					&types.Attribute{
-						Name:         "tags",
-						BlockComment: "Complex tags with expressions and functions",
+						Name:     "tags",
+						Comments: types.Comments{Leading: comments("// Complex tags with expressions and functions")},
 						Value: &types.FunctionCallExpr{
 							Name: "merge",
 							Args: []types.Expression{
@@ -1009,8 +1009,8 @@ func createComplexLocalsExpected() types.Body {
 	return &types.Root{
 		Children: []types.Body{
 			&types.Block{
-				Type:         "locals",
-				BlockComment: "Complex locals with nested expressions",
+				Type:     "locals",
+				Comments: types.Comments{Leading: comments("// Complex locals with nested expressions")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name: "subnet_map",
@@ -1060,7 +1060,7 @@ func createComplexLocalsExpected() types.Body {
 								},
 							},
 						},
-						BlockComment: "Complex map transformation",
+						Comments: types.Comments{Leading: comments("// Complex map transformation")},
 					},
 					&types.Attribute{
 						Name: "filtered_instances",
@@ -1132,7 +1132,7 @@ func createComplexLocalsExpected() types.Body {
 								},
 							},
 						},
-						BlockComment: "Nested for expressions with filtering",
+						Comments: types.Comments{Leading: comments("// Nested for expressions with filtering")},
 					},
 					&types.Attribute{
 						Name: "backup_config",
@@ -1266,7 +1266,7 @@ func createComplexLocalsExpected() types.Body {
 							},
 							FalseExpr: &types.LiteralValue{Value: nil, ValueType: "null"},
 						},
-						BlockComment: "Complex conditional with multiple nested expressions",
+						Comments: types.Comments{Leading: comments("// Complex conditional with multiple nested expressions")},
 					},
 					&types.Attribute{
 						Name: "naming_convention",
@@ -1298,7 +1298,7 @@ func createComplexLocalsExpected() types.Body {
 								},
 							},
 						},
-						BlockComment: "Complex string interpolation with functions",
+						Comments: types.Comments{Leading: comments("// Complex string interpolation with functions")},
 					},
 					&types.Attribute{
 						Name: "timeout_seconds",
@@ -1337,7 +1337,7 @@ func createComplexLocalsExpected() types.Body {
 								},
 							},
 						},
-						BlockComment: "Nested ternary operators",
+						Comments: types.Comments{Leading: comments("// Nested ternary operators")},
 					},
 					&types.Attribute{
 						Name: "schema",
@@ -1495,7 +1495,7 @@ func createComplexLocalsExpected() types.Body {
 								},
 							},
 						},
-						BlockComment: "Complex type expressions",
+						Comments: types.Comments{Leading: comments("// Complex type expressions")},
 					},
 				},
 			},
@@ -1508,9 +1508,9 @@ func createComplexDataSourceExpected() types.Body {
 	return &types.Root{
 		Children: []types.Body{
 			&types.Block{
-				Type:         "data",
-				Labels:       []string{"aws_iam_policy_document", "complex"},
-				BlockComment: "Data source with complex expressions",
+				Type:     "data",
+				Labels:   []string{"aws_iam_policy_document", "complex"},
+				Comments: types.Comments{Leading: comments("// Data source with complex expressions")},
 				Children: []types.Body{
 					&types.Block{
 						Type:   "dynamic",
@@ -1646,7 +1646,7 @@ func createComplexDataSourceExpected() types.Body {
 								},
 							},
 						},
-						BlockComment: "Dynamic statement blocks",
+						Comments: types.Comments{Leading: comments("// Dynamic statement blocks")},
 					},
 					&types.Block{
 						Type: "statement",
@@ -1755,7 +1755,7 @@ func createComplexDataSourceExpected() types.Body {
 								},
 							},
 						},
-						BlockComment: "Override with inline statement",
+						Comments: types.Comments{Leading: comments("// Override with inline statement")},
 					},
 				},
 			},
@@ -1768,9 +1768,9 @@ func createComplexVariableExpected() types.Body {
 	return &types.Root{
 		Children: []types.Body{
 			&types.Block{
-				Type:         "variable",
-				Labels:       []string{"complex_var"},
-				BlockComment: "Variable with complex type constraints and validations",
+				Type:     "variable",
+				Labels:   []string{"complex_var"},
+				Comments: types.Comments{Leading: comments("// Variable with complex type constraints and validations")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name:  "description",
@@ -2284,9 +2284,9 @@ func createComplexOutputExpected() types.Body {
 	return &types.Root{
 		Children: []types.Body{
 			&types.Block{
-				Type:         "output",
-				Labels:       []string{"complex_output"},
-				BlockComment: "Output with complex expressions",
+				Type:     "output",
+				Labels:   []string{"complex_output"},
+				Comments: types.Comments{Leading: comments("// Output with complex expressions")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name:  "description",
@@ -2419,9 +2419,9 @@ func createComplexProviderExpected() types.Body {
 	return &types.Root{
 		Children: []types.Body{
 			&types.Block{
-				Type:         "provider",
-				Labels:       []string{"aws"},
-				BlockComment: "Provider configuration with complex expressions",
+				Type:     "provider",
+				Labels:   []string{"aws"},
+				Comments: types.Comments{Leading: comments("// Provider configuration with complex expressions")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name:  "region",
@@ -2617,8 +2617,8 @@ func createComplexTerraformConfigExpected() types.Body {
 	return &types.Root{
 		Children: []types.Body{
 			&types.Block{
-				Type:         "terraform",
-				BlockComment: "Terraform configuration with complex expressions",
+				Type:     "terraform",
+				Comments: types.Comments{Leading: comments("// Terraform configuration with complex expressions")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name:  "required_version",
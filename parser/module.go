@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vahid-haghighat/terralint/parser/types"
+)
+
+// ParseModule parses the .tf files of a directory, without the ones of its
+// subdirectories, into the module they make up. The files are added in the
+// order of their names, like Terraform loads them.
+func ParseModule(dir string) (*types.Module, error) {
+	return ParseModuleLenient(dir, nil)
+}
+
+// ParseModuleLenient parses the module of a directory like ParseModule, but
+// leaves out the files that don't parse when lenient reports true for their
+// path instead of failing
+func ParseModuleLenient(dir string, lenient func(path string) bool) (*types.Module, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module: %w", err)
+	}
+
+	return parseModule(dir, entries, filepath.Join, os.ReadFile, lenient)
+}
+
+// ParseModuleFS parses the .tf files of the directory dir of fsys into the
+// module they make up
+func ParseModuleFS(fsys fs.FS, dir string) (*types.Module, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module: %w", err)
+	}
+
+	return parseModule(dir, entries, path.Join, func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}, nil)
+}
+
+func parseModule(dir string, entries []fs.DirEntry, join func(...string) string, readFile func(string) ([]byte, error), lenient func(string) bool) (*types.Module, error) {
+	module := types.NewModule(dir)
+	for _, entry := range entries {
+		if entry.IsDir() || !isModuleFile(entry.Name()) {
+			continue
+		}
+
+		filePath := join(dir, entry.Name())
+		content, err := readFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		root, err := ParseSource(content, filePath)
+		if err != nil {
+			if lenient != nil && lenient(filePath) {
+				continue
+			}
+			return nil, err
+		}
+
+		module.AddFile(&types.ModuleFile{
+			Path:     filePath,
+			Content:  content,
+			Root:     root,
+			Override: types.IsOverrideFile(filePath),
+		})
+	}
+	return module, nil
+}
+
+// isModuleFile reports whether Terraform loads the file with the given name as
+// part of a module. Hidden files and the backups of editors are left out.
+func isModuleFile(name string) bool {
+	return strings.HasSuffix(name, ".tf") && !strings.HasPrefix(name, ".") && !strings.HasPrefix(name, "#")
+}
@@ -1,15 +1,18 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
-	"math/big"
+	"io/fs"
+	"math"
 	"os"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
-	sitter "github.com/smacker/go-tree-sitter"
 	"github.com/vahid-haghighat/terralint/parser/types"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -27,12 +30,30 @@ func ParseTerraformFile(filePath string) (*types.Root, error) {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
+	return ParseSource(content, filePath)
+}
+
+// ParseFS reads a Terraform file from fsys and parses it into an AST
+func ParseFS(fsys fs.FS, name string) (*types.Root, error) {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return ParseSource(content, name)
+}
+
+// ParseSource parses Terraform source held in memory into an AST. The filename
+// is only used for source ranges and error messages, it is never read.
+func ParseSource(content []byte, filename string) (*types.Root, error) {
 	// Parse the file using HCL's native parser with comments enabled
-	file, diags := hclsyntax.ParseConfig(content, filePath, hcl.InitialPos)
+	file, diags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
 	if diags.HasErrors() {
-		return nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+		return nil, fmt.Errorf("failed to parse HCL: %w", diags)
 	}
 
+	c := newConverter(content, filename)
+
 	// Create root node
 	root := &types.Root{
 		Children: make([]types.Body, 0),
@@ -41,154 +62,336 @@ func ParseTerraformFile(filePath string) (*types.Root, error) {
 	// Get the root body
 	body := file.Body.(*hclsyntax.Body)
 
-	// Process attributes and blocks in order by their source position
-	var items []hclsyntax.Node
-	for name, attr := range body.Attributes {
-		items = append(items, &hclsyntax.Attribute{
-			Name:        name,
-			Expr:        attr.Expr,
-			SrcRange:    attr.SrcRange,
-			NameRange:   attr.NameRange,
-			EqualsRange: attr.EqualsRange,
-		})
-	}
-	for _, block := range body.Blocks {
-		items = append(items, block)
-	}
-
-	// Sort items by their source position
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].Range().Start.Line < items[j].Range().Start.Line ||
-			(items[i].Range().Start.Line == items[j].Range().Start.Line &&
-				items[i].Range().Start.Column < items[j].Range().Start.Column)
-	})
-
-	// If there are no items but there are comments at the start of the file,
-	// create an empty block with those comments
-	if len(items) == 0 {
-		tokens, diags := hclsyntax.LexConfig(content, filePath, hcl.InitialPos)
-		if !diags.HasErrors() {
-			var comments []string
-			for _, token := range tokens {
-				if token.Type == hclsyntax.TokenComment {
-					comment := string(token.Bytes)
-					if len(comment) > 0 {
-						comments = append(comments, comment)
-					}
-				}
-			}
-			if len(comments) > 0 {
-				var strippedComments []string
-				for _, comment := range comments {
-					strippedComments = append(strippedComments, stripCommentPrefix(comment))
-				}
-				root.Children = append(root.Children, &types.Block{
-					BlockComment: strings.Join(strippedComments, "\n"),
-				})
-			}
-		}
-		return root, nil
-	}
-
 	// Process items in order
-	var lastEndLine int
-	for i, item := range items {
-		// For the first item, we want to include all comments from the start of the file
-		var startLine int
-		if i == 0 {
-			startLine = 1
-		} else {
-			startLine = lastEndLine + 1
-		}
-
+	for _, item := range sortedBodyItems(body) {
 		switch item := item.(type) {
 		case *hclsyntax.Attribute:
-			attribute, err := convertAttribute(item.Name, item, content, startLine, item.Range().Start.Line)
+			attribute, err := c.convertAttribute(item.Name, item)
 			if err != nil {
 				return nil, fmt.Errorf("failed to convert attribute %s: %w", item.Name, err)
 			}
 			root.Children = append(root.Children, attribute)
-			lastEndLine = item.Range().End.Line
 		case *hclsyntax.Block:
-			block, err := convertBlock(item, content, startLine, item.Range().Start.Line)
+			block, err := c.convertBlock(item)
 			if err != nil {
 				return nil, fmt.Errorf("failed to convert block: %w", err)
 			}
 			root.Children = append(root.Children, block)
-			lastEndLine = item.Range().End.Line
 		}
 	}
 
+	// Whatever the items left behind sits between them or after the last one
+	root.Children = c.directives(root.Children, 0, len(content))
+	c.header(root)
+	c.attach(bodySlots(root.Children), 0, len(content), &root.Comments)
+
 	return root, nil
 }
 
-func isWhitespace(b byte) bool {
-	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+// converter turns the hclsyntax AST of a single file into a types AST. The file
+// is lexed once and every comment token is handed to exactly one node.
+type converter struct {
+	content  []byte
+	filename string
+	tokens   hclsyntax.Tokens
+	comments hclsyntax.Tokens // Comment tokens in source order
+	claimed  []bool           // Whether comments[i] is attached to a node
 }
 
-func stripCommentPrefix(comment string) string {
-	// Remove trailing newlines first
-	comment = strings.TrimRight(comment, "\n")
+func newConverter(content []byte, filename string) *converter {
+	c := &converter{
+		content:  content,
+		filename: filename,
+	}
+
+	// Lexing errors would already have been reported by the parser
+	c.tokens, _ = hclsyntax.LexConfig(content, filename, hcl.InitialPos)
 
-	// Strip comment prefix
-	if strings.HasPrefix(comment, "//") {
-		return strings.TrimSpace(comment[2:])
-	} else if strings.HasPrefix(comment, "#") {
-		return strings.TrimSpace(comment[1:])
+	for _, token := range c.tokens {
+		if token.Type == hclsyntax.TokenComment && len(token.Bytes) > 0 {
+			c.comments = append(c.comments, token)
+		}
 	}
-	return strings.TrimSpace(comment)
+	c.claimed = make([]bool, len(c.comments))
+
+	return c
 }
 
-func convertBlock(block *hclsyntax.Block, content []byte, startLine, endLine int) (*types.Block, error) {
-	// Extract comments for the block
-	var blockComment string
-	if startLine < endLine {
-		tokens, diags := hclsyntax.LexConfig(content, block.TypeRange.Filename, hcl.InitialPos)
-		if !diags.HasErrors() {
-			var comments []string
-			for _, token := range tokens {
-				if token.Type == hclsyntax.TokenComment {
-					if token.Range.Start.Line >= startLine && token.Range.Start.Line < endLine {
-						comment := string(token.Bytes)
-						if len(comment) > 0 {
-							comments = append(comments, comment)
-						}
-					}
-				}
-			}
-			if len(comments) > 0 {
-				var strippedComments []string
-				for _, comment := range comments {
-					strippedComments = append(strippedComments, stripCommentPrefix(comment))
-				}
-				blockComment = strings.Join(strippedComments, "\n")
-			}
+// commentSlot is a node that comments can be attached to
+type commentSlot struct {
+	rng      hcl.Range
+	comments *types.Comments
+}
+
+func bodySlots(children []types.Body) []commentSlot {
+	slots := make([]commentSlot, 0, len(children))
+	for _, child := range children {
+		switch c := child.(type) {
+		case *types.Block:
+			slots = append(slots, commentSlot{c.Range, &c.Comments})
+		case *types.Attribute:
+			slots = append(slots, commentSlot{c.Range, &c.Comments})
+		case *types.FormatDirective:
+			slots = append(slots, commentSlot{c.Range, &c.Comments})
 		}
 	}
+	return slots
+}
+
+func bodyRange(child types.Body) hcl.Range {
+	switch c := child.(type) {
+	case *types.Block:
+		return c.Range
+	case *types.Attribute:
+		return c.Range
+	case *types.FormatDirective:
+		return c.Range
+	}
+	return hcl.Range{}
+}
 
-	result := &types.Block{
-		Type:         block.Type,
-		Labels:       block.Labels,
-		Range:        block.Range(),
-		Children:     make([]types.Body, 0),
-		BlockComment: blockComment,
-	}
-
-	// Process inline comment
-	tokens, diags := hclsyntax.LexConfig(content, block.TypeRange.Filename, hcl.InitialPos)
-	if !diags.HasErrors() {
-		for _, token := range tokens {
-			if token.Type == hclsyntax.TokenComment &&
-				token.Range.Start.Line == block.TypeRange.Start.Line {
-				result.InlineComment = stripCommentPrefix(string(token.Bytes))
-				break
-			}
+// unclaimed returns the indexes of the comments that start in the byte range
+// [start, end) and are not attached to a node yet
+func (c *converter) unclaimed(start, end int) []int {
+	var found []int
+	i := sort.Search(len(c.comments), func(i int) bool {
+		return c.comments[i].Range.Start.Byte >= start
+	})
+	for ; i < len(c.comments) && c.comments[i].Range.Start.Byte < end; i++ {
+		if !c.claimed[i] {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// claim marks a comment as attached and adds it to list in source order
+func (c *converter) claim(i int, list []types.Comment) []types.Comment {
+	c.claimed[i] = true
+
+	// Line comments own the newline that ends them, the printer adds its own
+	token := c.comments[i]
+	text := strings.TrimRightFunc(string(token.Bytes), unicode.IsSpace)
+	end := token.Range.Start
+	end.Byte += len(text)
+	if newline := strings.LastIndexByte(text, '\n'); newline >= 0 {
+		end.Line += strings.Count(text, "\n")
+		end.Column = utf8.RuneCountInString(text[newline+1:]) + 1
+	} else {
+		end.Column += utf8.RuneCountInString(text)
+	}
+	comment := types.Comment{
+		Text:  text,
+		Range: hcl.Range{Filename: token.Range.Filename, Start: token.Range.Start, End: end},
+	}
+
+	at := sort.Search(len(list), func(j int) bool {
+		return list[j].Range.Start.Byte > comment.Range.Start.Byte
+	})
+	return append(list[:at], append([]types.Comment{comment}, list[at:]...)...)
+}
+
+// attach hands the unclaimed comments in the byte range [start, end) that lie
+// between the given nodes to them. A block comment right before a node on its
+// line leads that node, any other comment on the line a node ends on trails
+// that node, the rest lead the node after them and the comments after the last
+// node dangle in the container.
+func (c *converter) attach(nodes []commentSlot, start, end int, container *types.Comments) {
+	next := 0
+	for _, i := range c.unclaimed(start, end) {
+		pos := c.comments[i].Range.Start
+		for next < len(nodes) && nodes[next].rng.End.Byte <= pos.Byte {
+			next++
+		}
+		if next < len(nodes) && nodes[next].rng.Start.Byte <= pos.Byte {
+			// Comments inside a node are left to the node itself
+			continue
+		}
+
+		switch {
+		case next < len(nodes) && c.inlineBefore(i, nodes[next].rng):
+			nodes[next].comments.Leading = c.claim(i, nodes[next].comments.Leading)
+		case next > 0 && nodes[next-1].rng.End.Line == pos.Line:
+			nodes[next-1].comments.Trailing = c.claim(i, nodes[next-1].comments.Trailing)
+		case next < len(nodes):
+			nodes[next].comments.Leading = c.claim(i, nodes[next].comments.Leading)
+		default:
+			container.Dangling = c.claim(i, container.Dangling)
+		}
+	}
+}
+
+// header hands the comments before the first item of the file that a blank
+// line sets off from it to the root, so they stay at the top of the file
+// whatever happens to that item
+func (c *converter) header(root *types.Root) {
+	if len(root.Children) == 0 {
+		return
+	}
+	first := bodyRange(root.Children[0])
+	found := c.unclaimed(0, first.Start.Byte)
+
+	last := -1
+	for j, i := range found {
+		next := first.Start.Line
+		if j+1 < len(found) {
+			next = c.comments[found[j+1]].Range.Start.Line
+		}
+		if next-c.endLine(i) > 1 {
+			last = j
+		}
+	}
+	for _, i := range found[:last+1] {
+		root.Comments.Leading = c.claim(i, root.Comments.Leading)
+	}
+}
+
+// endLine returns the line the comment at index i ends on, without the
+// newline that ends a line comment
+func (c *converter) endLine(i int) int {
+	token := c.comments[i]
+	text := strings.TrimRightFunc(string(token.Bytes), unicode.IsSpace)
+	return token.Range.Start.Line + strings.Count(text, "\n")
+}
+
+// inlineBefore reports whether the comment at index i is a block comment that
+// only spaces separate from the start of rng
+func (c *converter) inlineBefore(i int, rng hcl.Range) bool {
+	token := c.comments[i]
+	if !bytes.HasPrefix(token.Bytes, []byte("/*")) || token.Range.End.Byte > rng.Start.Byte {
+		return false
+	}
+	return len(bytes.Trim(c.content[token.Range.End.Byte:rng.Start.Byte], " \t")) == 0
+}
+
+// attachOpening hands the comments that follow an opening brace or bracket on
+// its line to the node it opens, unless the first item shares that line
+func (c *converter) attachOpening(open hcl.Range, first *commentSlot, end int, comments *types.Comments) {
+	if first != nil && first.rng.Start.Line == open.End.Line {
+		return
+	}
+	for _, i := range c.unclaimed(open.End.Byte, end) {
+		if c.comments[i].Range.Start.Line == open.End.Line {
+			comments.Trailing = c.claim(i, comments.Trailing)
+		}
+	}
+}
+
+// attachInside hands the comments left inside a node, which sit in expressions
+// that can't hold comments themselves, to the node. On the line the node
+// starts on they trail it, anywhere else they lead it.
+func (c *converter) attachInside(rng hcl.Range, comments *types.Comments) {
+	for _, i := range c.unclaimed(rng.Start.Byte, rng.End.Byte) {
+		if c.comments[i].Range.Start.Line == rng.Start.Line {
+			comments.Trailing = c.claim(i, comments.Trailing)
+		} else {
+			comments.Leading = c.claim(i, comments.Leading)
+		}
+	}
+}
+
+// attachItems attaches the comments inside a collection that spans from the
+// open range to end to its items and to the collection itself
+func (c *converter) attachItems(slots []commentSlot, open hcl.Range, end int, comments *types.Comments) {
+	for _, slot := range slots {
+		c.attachInside(slot.rng, slot.comments)
+	}
+
+	var first *commentSlot
+	if len(slots) > 0 {
+		first = &slots[0]
+	}
+	c.attachOpening(open, first, end, comments)
+	c.attach(slots, open.End.Byte, end, comments)
+}
+
+// attachList attaches the comments inside a tuple or an argument list that
+// spans from the open range to end to its items and to the list itself, and
+// returns the comments of each item, or nil when no item has any
+func (c *converter) attachList(exprs []hclsyntax.Expression, open hcl.Range, end int, comments *types.Comments) []types.Comments {
+	itemComments := make([]types.Comments, len(exprs))
+	slots := make([]commentSlot, len(exprs))
+	for i, expr := range exprs {
+		slots[i] = commentSlot{expr.Range(), &itemComments[i]}
+	}
+	c.attachItems(slots, open, end, comments)
+	for _, attached := range itemComments {
+		if !attached.Empty() {
+			return itemComments
+		}
+	}
+	return nil
+}
+
+// directives turns the unclaimed comments in the byte range [start, end) of a
+// body that hold a directive on a line of their own into FormatDirective items
+// and adds them to children in source order
+func (c *converter) directives(children []types.Body, start, end int) []types.Body {
+	for _, i := range c.unclaimed(start, end) {
+		token := c.comments[i]
+		if !c.ownLine(token.Range.Start.Byte) {
+			continue
+		}
+		directive := parseDirective(string(token.Bytes))
+		if directive == nil {
+			continue
+		}
+		comment := c.claim(i, nil)[0]
+		directive.Text, directive.Range = comment.Text, comment.Range
+
+		at := sort.Search(len(children), func(j int) bool {
+			return bodyRange(children[j]).Start.Byte > directive.Range.Start.Byte
+		})
+		children = append(children[:at], append([]types.Body{directive}, children[at:]...)...)
+	}
+	return children
+}
+
+// ownLine reports whether only whitespace precedes the byte offset on its line
+func (c *converter) ownLine(offset int) bool {
+	lineStart := bytes.LastIndexByte(c.content[:offset], '\n') + 1
+	return len(bytes.TrimSpace(c.content[lineStart:offset])) == 0
+}
+
+// parseDirective returns the directive held by the text of a line comment, or
+// nil for an ordinary comment. Parameters are separated by spaces or commas.
+func parseDirective(text string) *types.FormatDirective {
+	switch {
+	case strings.HasPrefix(text, "#"):
+		text = text[1:]
+	case strings.HasPrefix(text, "//"):
+		text = text[2:]
+	default:
+		return nil
+	}
+
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+
+	directive := &types.FormatDirective{DirectiveType: fields[0]}
+	if len(fields) > 1 {
+		directive.Parameters = fields[1:]
+	}
+
+	switch directive.DirectiveType {
+	case types.DirectiveIgnore, types.DirectiveIgnoreFile:
+		return directive
+	case types.DirectiveFormat:
+		if directive.FormattingOff() || directive.FormattingOn() {
+			return directive
 		}
 	}
+	return nil
+}
 
-	// Process attributes and blocks in order by their source position
+// sortedBodyItems returns the attributes and blocks of a body ordered by their source position
+func sortedBodyItems(body *hclsyntax.Body) []hclsyntax.Node {
 	var items []hclsyntax.Node
-	for name, attr := range block.Body.Attributes {
+	for name, attr := range body.Attributes {
 		items = append(items, &hclsyntax.Attribute{
 			Name:        name,
 			Expr:        attr.Expr,
@@ -197,8 +400,8 @@ func convertBlock(block *hclsyntax.Block, content []byte, startLine, endLine int
 			EqualsRange: attr.EqualsRange,
 		})
 	}
-	for _, nestedBlock := range block.Body.Blocks {
-		items = append(items, nestedBlock)
+	for _, block := range body.Blocks {
+		items = append(items, block)
 	}
 
 	// Sort items by their source position
@@ -208,162 +411,81 @@ func convertBlock(block *hclsyntax.Block, content []byte, startLine, endLine int
 				items[i].Range().Start.Column < items[j].Range().Start.Column)
 	})
 
-	// Process items in order
-	var lastChildEndLine int = block.Body.Range().Start.Line
-	for _, item := range items {
-		var itemStartLine int = lastChildEndLine + 1
+	return items
+}
 
+func (c *converter) convertBlock(block *hclsyntax.Block) (*types.Block, error) {
+	result := &types.Block{
+		Type:     block.Type,
+		Labels:   block.Labels,
+		Range:    block.Range(),
+		Children: make([]types.Body, 0),
+	}
+
+	// Process items in order
+	for _, item := range sortedBodyItems(block.Body) {
 		switch item := item.(type) {
 		case *hclsyntax.Attribute:
-			attribute, err := convertAttribute(item.Name, item, content, itemStartLine, item.Range().Start.Line)
+			attribute, err := c.convertAttribute(item.Name, item)
 			if err != nil {
 				return nil, fmt.Errorf("failed to convert attribute %s: %w", item.Name, err)
 			}
 			result.Children = append(result.Children, attribute)
-			lastChildEndLine = item.Range().End.Line
 		case *hclsyntax.Block:
-			nested, err := convertBlock(item, content, itemStartLine, item.Range().Start.Line)
+			nested, err := c.convertBlock(item)
 			if err != nil {
 				return nil, fmt.Errorf("failed to convert nested block: %w", err)
 			}
 			result.Children = append(result.Children, nested)
-			lastChildEndLine = item.Range().End.Line
 		}
 	}
 
+	result.Children = c.directives(result.Children, block.OpenBraceRange.End.Byte, block.CloseBraceRange.Start.Byte)
+	c.attachItems(bodySlots(result.Children), block.OpenBraceRange, block.CloseBraceRange.Start.Byte, &result.Comments)
+	c.attachInside(result.Range, &result.Comments)
+
 	return result, nil
 }
 
-func convertAttribute(name string, attr *hclsyntax.Attribute, content []byte, startLine int, endLine int) (*types.Attribute, error) {
-	expr, err := convertExpression(attr.Expr)
+func (c *converter) convertAttribute(name string, attr *hclsyntax.Attribute) (*types.Attribute, error) {
+	expr, err := c.convertExpression(attr.Expr)
 	if err != nil {
 		return nil, err
 	}
 
-	a := &types.Attribute{
+	result := &types.Attribute{
 		Name:  name,
 		Value: expr,
 		Range: attr.Range(),
 	}
+	c.attachInside(result.Range, &result.Comments)
 
-	// Extract comments using HCL's token functionality
-	tokens, diags := hclsyntax.LexConfig(content, attr.NameRange.Filename, hcl.InitialPos)
-	if !diags.HasErrors() {
-		// Process all comments before the attribute
-		var comments []string
-		for _, token := range tokens {
-			if token.Type == hclsyntax.TokenComment {
-				if token.Range.Start.Line >= startLine && token.Range.Start.Line < endLine {
-					comment := string(token.Bytes)
-					if len(comment) > 0 {
-						comments = append(comments, comment)
-					}
-				}
-			}
-		}
-
-		// Set the block comment if we found any
-		if len(comments) > 0 {
-			var strippedComments []string
-			for _, comment := range comments {
-				strippedComments = append(strippedComments, stripCommentPrefix(comment))
-			}
-			a.BlockComment = strings.Join(strippedComments, "\n")
-		}
-	}
-
-	// Process inline comment
-	tokens, diags = hclsyntax.LexConfig(content, attr.NameRange.Filename, hcl.InitialPos)
-	if !diags.HasErrors() {
-		for _, token := range tokens {
-			if token.Type == hclsyntax.TokenComment &&
-				token.Range.Start.Line == attr.NameRange.Start.Line {
-				a.InlineComment = stripCommentPrefix(string(token.Bytes))
-				break
-			}
-		}
-	}
-
-	return a, nil
+	return result, nil
 }
 
-func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
+func (c *converter) convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 	switch e := expr.(type) {
 	case *hclsyntax.LiteralValueExpr:
-		var value interface{}
-		switch {
-		case e.Val.Type() == cty.String:
-			value = e.Val.AsString()
-		case e.Val.Type() == cty.Bool:
-			value = e.Val.True()
-		case e.Val.Type() == cty.Number:
-			bf := e.Val.AsBigFloat()
-			if bf.IsInt() {
-				i, _ := bf.Int64()
-				value = i
-			} else {
-				f, _ := bf.Float64()
-				value = f
-			}
-		default:
-			value = e.Val.GoString()
+		literal := convertLiteralValue(e.Val, e.Range())
+		if literal.ValueType == "number" {
+			literal.Token = string(c.content[e.SrcRange.Start.Byte:e.SrcRange.End.Byte])
 		}
-		return &types.LiteralValue{
-			Value:     value,
-			ValueType: e.Val.Type().FriendlyName(),
-			ExprRange: e.Range(),
-		}, nil
+		return literal, nil
 	case *hclsyntax.TemplateExpr:
-		// Check if this is a single literal value
-		if len(e.Parts) == 1 {
-			if lit, ok := e.Parts[0].(*hclsyntax.LiteralValueExpr); ok {
-				var value interface{}
-				switch {
-				case lit.Val.Type() == cty.String:
-					value = lit.Val.AsString()
-				case lit.Val.Type() == cty.Bool:
-					value = lit.Val.True()
-				case lit.Val.Type() == cty.Number:
-					bf := lit.Val.AsBigFloat()
-					if bf.IsInt() {
-						i, _ := bf.Int64()
-						value = i
-					} else {
-						f, _ := bf.Float64()
-						value = f
-					}
-				default:
-					value = lit.Val.GoString()
-				}
-				return &types.LiteralValue{
-					Value:     value,
-					ValueType: lit.Val.Type().FriendlyName(),
-					ExprRange: e.Range(),
-				}, nil
-			}
+		if i := c.tokenIndex(e.SrcRange.Start.Byte); i < len(c.tokens) && c.tokens[i].Type == hclsyntax.TokenOHeredoc {
+			return c.convertHeredoc(e, i)
 		}
 
-		// Check if this is a heredoc by looking at the source code
-		srcRange := e.Range()
-		if srcRange.Start.Line != srcRange.End.Line && len(e.Parts) > 0 {
-			// This is a multi-line template, treat it as a heredoc
+		// Check if this is a single literal value
+		if len(e.Parts) == 1 {
 			if lit, ok := e.Parts[0].(*hclsyntax.LiteralValueExpr); ok {
-				return &types.HeredocExpr{
-					Marker:    "EOT", // Default marker
-					Content:   lit.Val.AsString(),
-					Indented:  false,
-					ExprRange: e.Range(),
-				}, nil
+				return convertLiteralValue(lit.Val, e.Range()), nil
 			}
 		}
 
-		parts := make([]types.Expression, len(e.Parts))
-		for i, part := range e.Parts {
-			converted, err := convertExpression(part)
-			if err != nil {
-				return nil, err
-			}
-			parts[i] = converted
+		parts, err := c.templateParts(e.Parts, &templateSource{tags: templateTags(c.tokensIn(e.SrcRange))})
+		if err != nil {
+			return nil, err
 		}
 		return &types.TemplateExpr{
 			Parts:     parts,
@@ -385,74 +507,55 @@ func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 			}
 		}
 
-		// Build parts for traversal
-		parts := make([]string, len(e.Traversal))
+		// Everything from the first index on becomes a relative traversal of
+		// the names that precede it
+		split := len(e.Traversal)
 		for i, traverser := range e.Traversal {
+			if _, ok := traverser.(hcl.TraverseIndex); ok {
+				split = i
+				break
+			}
+		}
+
+		parts := make([]string, split)
+		for i, traverser := range e.Traversal[:split] {
 			switch t := traverser.(type) {
 			case hcl.TraverseRoot:
 				parts[i] = t.Name
 			case hcl.TraverseAttr:
 				parts[i] = t.Name
-			case hcl.TraverseIndex:
-				switch t.Key.Type() {
-				case cty.Number:
-					bf := t.Key.AsBigFloat()
-					if i, acc := bf.Int64(); acc == big.Exact {
-						parts[i] = fmt.Sprintf("[%d]", i)
-					} else {
-						parts[i] = fmt.Sprintf("[%s]", bf.String())
-					}
-				case cty.String:
-					parts[i] = fmt.Sprintf("[%q]", t.Key.AsString())
-				}
-			}
-		}
-
-		// If any part is an index expression, return a relative traversal
-		hasIndex := false
-		for _, part := range parts {
-			if strings.HasPrefix(part, "[") {
-				hasIndex = true
-				break
 			}
 		}
 
-		if hasIndex {
-			// Split parts into source and traversal
-			source := &types.ReferenceExpr{
-				Parts:     parts[:1],
-				ExprRange: e.Range(),
-			}
-			traversal := make([]types.TraversalElem, len(parts)-1)
-			for i, part := range parts[1:] {
-				if strings.HasPrefix(part, "[") {
-					traversal[i] = types.TraversalElem{
-						Type: "index",
-						Name: part,
-					}
-				} else {
-					traversal[i] = types.TraversalElem{
-						Type: "attr",
-						Name: part,
-					}
-				}
-			}
-			return &types.RelativeTraversalExpr{
-				Source:    source,
-				Traversal: traversal,
+		if split == len(e.Traversal) {
+			return &types.ReferenceExpr{
+				Parts:     parts,
 				ExprRange: e.Range(),
 			}, nil
 		}
 
-		// Otherwise, return a reference
-		return &types.ReferenceExpr{
-			Parts:     parts,
+		return &types.RelativeTraversalExpr{
+			Source: &types.ReferenceExpr{
+				Parts:     parts,
+				ExprRange: hcl.RangeBetween(e.Traversal[0].SourceRange(), e.Traversal[split-1].SourceRange()),
+			},
+			Traversal: convertTraversal(e.Traversal[split:]),
+			ExprRange: e.Range(),
+		}, nil
+	case *hclsyntax.RelativeTraversalExpr:
+		source, err := c.convertExpression(e.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert traversal source: %w", err)
+		}
+		return &types.RelativeTraversalExpr{
+			Source:    source,
+			Traversal: convertTraversal(e.Traversal),
 			ExprRange: e.Range(),
 		}, nil
 	case *hclsyntax.FunctionCallExpr:
 		args := make([]types.Expression, len(e.Args))
 		for i, arg := range e.Args {
-			converted, err := convertExpression(arg)
+			converted, err := c.convertExpression(arg)
 			if err != nil {
 				return nil, err
 			}
@@ -460,110 +563,86 @@ func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 		}
 
 		// Create a function call expression
-		return &types.FunctionCallExpr{
-			Name:      e.Name,
-			Args:      args,
-			ExprRange: e.Range(),
-		}, nil
+		result := &types.FunctionCallExpr{
+			Name:        e.Name,
+			Args:        args,
+			ExpandFinal: e.ExpandFinal,
+			ExprRange:   e.Range(),
+		}
+		result.ArgComments = c.attachList(e.Args, e.OpenParenRange, e.CloseParenRange.Start.Byte, &result.Comments)
+		return result, nil
 	case *hclsyntax.ObjectConsExpr:
 		items := make([]types.ObjectItem, len(e.Items))
 
-		// Get the file content for lexing
-		content, err := os.ReadFile(e.SrcRange.Filename)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read file for comment parsing: %w", err)
-		}
-
-		// Get all tokens once
-		tokens, diags := hclsyntax.LexConfig(content, e.SrcRange.Filename, hcl.InitialPos)
-		if diags.HasErrors() {
-			return nil, fmt.Errorf("failed to lex file: %s", diags.Error())
-		}
-
 		for i, item := range e.Items {
-			key, err := convertExpression(item.KeyExpr)
+			key, err := c.convertExpression(item.KeyExpr)
 			if err != nil {
 				return nil, err
 			}
-			value, err := convertExpression(item.ValueExpr)
+			value, err := c.convertExpression(item.ValueExpr)
 			if err != nil {
 				return nil, err
 			}
 
-			// Extract comments for the object item
-			var blockComment string
-			var comments []string
-			var startLine int
-			if i == 0 {
-				startLine = e.SrcRange.Start.Line
-			} else {
-				prevItem := e.Items[i-1]
-				startLine = prevItem.ValueExpr.Range().End.Line
-			}
-
-			for _, token := range tokens {
-				if token.Type == hclsyntax.TokenComment {
-					if token.Range.Start.Line > startLine &&
-						token.Range.Start.Line < item.KeyExpr.Range().Start.Line {
-						comment := string(token.Bytes)
-						if len(comment) > 0 {
-							comments = append(comments, comment)
-						}
-					}
-				}
-			}
-			if len(comments) > 0 {
-				var strippedComments []string
-				for _, comment := range comments {
-					strippedComments = append(strippedComments, stripCommentPrefix(comment))
-				}
-				blockComment = strings.Join(strippedComments, "\n")
-			}
-
 			items[i] = types.ObjectItem{
-				Key:          key,
-				Value:        value,
-				BlockComment: blockComment,
+				Key:       key,
+				Value:     value,
+				ExprRange: hcl.RangeBetween(item.KeyExpr.Range(), item.ValueExpr.Range()),
 			}
 		}
-		return &types.ObjectExpr{
-			Items: items,
-		}, nil
+
+		result := &types.ObjectExpr{
+			Items:     items,
+			ExprRange: e.Range(),
+		}
+		slots := make([]commentSlot, len(items))
+		for i := range items {
+			slots[i] = commentSlot{items[i].ExprRange, &items[i].Comments}
+		}
+		c.attachItems(slots, e.OpenRange, e.SrcRange.End.Byte, &result.Comments)
+		return result, nil
 	case *hclsyntax.ObjectConsKeyExpr:
-		// For object keys, always create a ReferenceExpr
-		if traversal, ok := e.Wrapped.(*hclsyntax.ScopeTraversalExpr); ok {
-			parts := make([]string, len(traversal.Traversal))
-			for i, traverser := range traversal.Traversal {
-				switch t := traverser.(type) {
-				case hcl.TraverseRoot:
-					parts[i] = t.Name
-				case hcl.TraverseAttr:
-					parts[i] = t.Name
-				}
-			}
+		// A naked identifier is the key itself, anything else is evaluated
+		if keyword := hcl.ExprAsKeyword(e.Wrapped); keyword != "" && !e.ForceNonLiteral {
 			return &types.ReferenceExpr{
-				Parts: parts,
+				Parts:     []string{keyword},
+				ExprRange: e.Range(),
+			}, nil
+		}
+		// Unwrapped, the interpolation of a quoted key would read as an attribute name
+		if wrap, ok := e.Wrapped.(*hclsyntax.TemplateWrapExpr); ok {
+			wrapped, err := c.convertExpression(wrap.Wrapped)
+			if err != nil {
+				return nil, err
+			}
+			return &types.TemplateExpr{
+				Parts:     []types.Expression{wrapped},
+				ExprRange: wrap.Range(),
 			}, nil
 		}
-		return convertExpression(e.Wrapped)
+		return c.convertExpression(e.Wrapped)
 	case *hclsyntax.TupleConsExpr:
 		items := make([]types.Expression, len(e.Exprs))
 		for i, expr := range e.Exprs {
-			converted, err := convertExpression(expr)
+			converted, err := c.convertExpression(expr)
 			if err != nil {
 				return nil, err
 			}
 			items[i] = converted
 		}
-		return &types.ArrayExpr{
-			Items: items,
-		}, nil
+
+		result := &types.ArrayExpr{
+			Items:     items,
+			ExprRange: e.Range(),
+		}
+		result.ItemComments = c.attachList(e.Exprs, e.OpenRange, e.SrcRange.End.Byte, &result.Comments)
+		return result, nil
 	case *hclsyntax.BinaryOpExpr:
-		left, err := convertExpression(e.LHS)
+		left, err := c.convertExpression(e.LHS)
 		if err != nil {
 			return nil, err
 		}
-		right, err := convertExpression(e.RHS)
+		right, err := c.convertExpression(e.RHS)
 		if err != nil {
 			return nil, err
 		}
@@ -641,13 +720,17 @@ func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 		default:
 			return nil, fmt.Errorf("unsupported operator type: %s", e.Op.Type.FriendlyName())
 		}
-		return &types.BinaryExpr{
-			Left:     left,
-			Operator: operator,
-			Right:    right,
-		}, nil
+		result := &types.BinaryExpr{
+			Left:      left,
+			Operator:  operator,
+			Right:     right,
+			ExprRange: e.Range(),
+		}
+		operands := []commentSlot{{e.LHS.Range(), &result.LeftComments}, {e.RHS.Range(), &result.RightComments}}
+		c.attach(operands, e.LHS.Range().End.Byte, e.RHS.Range().Start.Byte, &result.RightComments)
+		return result, nil
 	case *hclsyntax.UnaryOpExpr:
-		expr, err := convertExpression(e.Val)
+		expr, err := c.convertExpression(e.Val)
 		if err != nil {
 			return nil, err
 		}
@@ -662,19 +745,20 @@ func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 			return nil, fmt.Errorf("unsupported unary operator type: %s", e.Op.Impl.Params()[0].Type.FriendlyName())
 		}
 		return &types.UnaryExpr{
-			Operator: operator,
-			Expr:     expr,
+			Operator:  operator,
+			Expr:      expr,
+			ExprRange: e.Range(),
 		}, nil
 	case *hclsyntax.ConditionalExpr:
-		condition, err := convertExpression(e.Condition)
+		condition, err := c.convertExpression(e.Condition)
 		if err != nil {
 			return nil, err
 		}
-		trueResult, err := convertExpression(e.TrueResult)
+		trueResult, err := c.convertExpression(e.TrueResult)
 		if err != nil {
 			return nil, err
 		}
-		falseResult, err := convertExpression(e.FalseResult)
+		falseResult, err := c.convertExpression(e.FalseResult)
 		if err != nil {
 			return nil, err
 		}
@@ -685,23 +769,23 @@ func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 			ExprRange: e.Range(),
 		}, nil
 	case *hclsyntax.ForExpr:
-		collection, err := convertExpression(e.CollExpr)
+		collection, err := c.convertExpression(e.CollExpr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert collection: %w", err)
 		}
-		thenValue, err := convertExpression(e.ValExpr)
+		thenValue, err := c.convertExpression(e.ValExpr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert value expression: %w", err)
 		}
 		var condition types.Expression
 		if e.CondExpr != nil {
-			condition, err = convertExpression(e.CondExpr)
+			condition, err = c.convertExpression(e.CondExpr)
 			if err != nil {
 				return nil, fmt.Errorf("failed to convert condition: %w", err)
 			}
 		}
 		if e.KeyExpr != nil {
-			thenKey, err := convertExpression(e.KeyExpr)
+			thenKey, err := c.convertExpression(e.KeyExpr)
 			if err != nil {
 				return nil, fmt.Errorf("failed to convert key expression: %w", err)
 			}
@@ -712,6 +796,8 @@ func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 				ThenKeyExpr:   thenKey,
 				ThenValueExpr: thenValue,
 				Condition:     condition,
+				Grouped:       e.Group,
+				ExprRange:     e.Range(),
 			}, nil
 		}
 		return &types.ForArrayExpr{
@@ -720,26 +806,31 @@ func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 			Collection:    collection,
 			ThenValueExpr: thenValue,
 			Condition:     condition,
+			ExprRange:     e.Range(),
 		}, nil
 	case *hclsyntax.SplatExpr:
-		source, err := convertExpression(e.Source)
+		source, err := c.convertExpression(e.Source)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert splat source: %w", err)
 		}
-		each, err := convertExpression(e.Each)
+		each, err := c.convertExpression(e.Each)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert splat each: %w", err)
 		}
 		return &types.SplatExpr{
-			Source: source,
-			Each:   each,
+			Source:    source,
+			Each:      each,
+			AttrOnly:  c.content[e.MarkerRange.Start.Byte] == '.',
+			ExprRange: e.Range(),
 		}, nil
+	case *hclsyntax.AnonSymbolExpr:
+		return &types.AnonSymbolExpr{ExprRange: e.Range()}, nil
 	case *hclsyntax.IndexExpr:
-		collection, err := convertExpression(e.Collection)
+		collection, err := c.convertExpression(e.Collection)
 		if err != nil {
 			return nil, err
 		}
-		key, err := convertExpression(e.Key)
+		key, err := c.convertExpression(e.Key)
 		if err != nil {
 			return nil, err
 		}
@@ -749,16 +840,18 @@ func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 			ExprRange:  e.Range(),
 		}, nil
 	case *hclsyntax.ParenthesesExpr:
-		expression, err := convertExpression(e.Expression)
+		expression, err := c.convertExpression(e.Expression)
 		if err != nil {
 			return nil, err
 		}
-		return &types.ParenExpr{
+		result := &types.ParenExpr{
 			Expression: expression,
 			ExprRange:  e.Range(),
-		}, nil
+		}
+		c.attach([]commentSlot{{e.Expression.Range(), &result.Comments}}, e.SrcRange.Start.Byte+1, e.SrcRange.End.Byte-1, &result.Comments)
+		return result, nil
 	case *hclsyntax.TemplateJoinExpr:
-		tuple, err := convertExpression(e.Tuple)
+		tuple, err := c.convertExpression(e.Tuple)
 		if err != nil {
 			return nil, err
 		}
@@ -767,7 +860,7 @@ func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 			ExprRange: e.Range(),
 		}, nil
 	case *hclsyntax.TemplateWrapExpr:
-		wrapped, err := convertExpression(e.Wrapped)
+		wrapped, err := c.convertExpression(e.Wrapped)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert wrapped expression: %w", err)
 		}
@@ -777,183 +870,394 @@ func convertExpression(expr hclsyntax.Expression) (types.Expression, error) {
 	}
 }
 
-func convertExpressions(exprs []hclsyntax.Expression) ([]types.Expression, error) {
-	result := make([]types.Expression, len(exprs))
-	for i, expr := range exprs {
-		converted, err := convertExpression(expr)
+// convertHeredoc converts a heredoc template that starts with the token at
+// c.tokens[open], keeping its marker and its content as written
+func (c *converter) convertHeredoc(e *hclsyntax.TemplateExpr, open int) (*types.HeredocExpr, error) {
+	end := c.tokenIndex(e.SrcRange.End.Byte - 1)
+	if end >= len(c.tokens) || c.tokens[end].Type != hclsyntax.TokenCHeredoc {
+		return nil, fmt.Errorf("missing end of the heredoc at %s", e.SrcRange)
+	}
+	body := c.tokens[open+1 : end]
+
+	marker := strings.TrimSpace(strings.TrimPrefix(string(c.tokens[open].Bytes), "<<"))
+	indented := strings.HasPrefix(marker, "-")
+
+	src := &templateSource{tags: templateTags(body)}
+	if indented {
+		src.indent = heredocIndent(body)
+	}
+	parts, err := c.templateParts(e.Parts, src)
+	if err != nil {
+		return nil, err
+	}
+
+	content := c.content[c.tokens[open].Range.End.Byte:c.tokens[end].Range.Start.Byte]
+	return &types.HeredocExpr{
+		Marker:    strings.TrimPrefix(marker, "-"),
+		Content:   trimIndent(string(content), src.indent),
+		Indented:  indented,
+		Parts:     parts,
+		ExprRange: e.Range(),
+	}, nil
+}
+
+// templateTag is a ${...} interpolation or %{...} directive at the top level of a template
+type templateTag struct {
+	keyword   string // The directive keyword, empty for interpolations
+	directive bool
+	strip     types.TemplateStrip
+	rng       hcl.Range
+}
+
+// templateSource is what converting the parts of a template needs from its tokens
+type templateSource struct {
+	tags   []templateTag
+	indent int // The indentation <<- removes from every line of a heredoc
+}
+
+// tokenIndex returns the index of the first token that ends after offset
+func (c *converter) tokenIndex(offset int) int {
+	return sort.Search(len(c.tokens), func(i int) bool {
+		return c.tokens[i].Range.End.Byte > offset
+	})
+}
+
+// tokensIn returns the tokens that overlap rng
+func (c *converter) tokensIn(rng hcl.Range) hclsyntax.Tokens {
+	start := c.tokenIndex(rng.Start.Byte)
+	end := start
+	for end < len(c.tokens) && c.tokens[end].Range.Start.Byte < rng.End.Byte {
+		end++
+	}
+	return c.tokens[start:end]
+}
+
+// templateTags returns the tags of a template in source order. Tags of
+// templates nested inside an interpolation are left to the nested template.
+func templateTags(tokens hclsyntax.Tokens) []templateTag {
+	var tags []templateTag
+	openIndex, depth := 0, 0
+
+	for i, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenTemplateInterp, hclsyntax.TokenTemplateControl:
+			if depth == 0 {
+				openIndex = i
+			}
+			depth++
+		case hclsyntax.TokenTemplateSeqEnd:
+			depth--
+			if depth != 0 {
+				continue
+			}
+
+			open := tokens[openIndex]
+			tag := templateTag{
+				directive: open.Type == hclsyntax.TokenTemplateControl,
+				strip: types.TemplateStrip{
+					Left:  bytes.HasSuffix(open.Bytes, []byte("~")),
+					Right: bytes.HasPrefix(token.Bytes, []byte("~")),
+				},
+				rng: hcl.RangeBetween(open.Range, token.Range),
+			}
+			if tag.directive && tokens[openIndex+1].Type == hclsyntax.TokenIdent {
+				tag.keyword = string(tokens[openIndex+1].Bytes)
+			}
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// heredocIndent returns the indentation that <<- removes from every line of a
+// heredoc, which is the smallest indentation of a line that isn't blank. A line
+// that starts with an interpolation or a directive has no indentation.
+func heredocIndent(tokens hclsyntax.Tokens) int {
+	indent, newline, depth := math.MaxInt, true, 0
+	for _, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenTemplateInterp, hclsyntax.TokenTemplateControl:
+			if depth == 0 && newline {
+				indent, newline = 0, false
+			}
+			depth++
+		case hclsyntax.TokenTemplateSeqEnd:
+			depth--
+		case hclsyntax.TokenStringLit:
+			if depth != 0 {
+				continue
+			}
+			line := string(token.Bytes)
+			if trimmed := strings.TrimLeftFunc(line, unicode.IsSpace); newline && (trimmed != "" || !strings.HasSuffix(line, "\n")) {
+				indent = min(indent, utf8.RuneCountInString(line[:len(line)-len(trimmed)]))
+			}
+			newline = strings.HasSuffix(line, "\n")
+		}
+	}
+
+	if indent == math.MaxInt {
+		return 0
+	}
+	return indent
+}
+
+// trimIndent removes up to n whitespace characters from the start of every line
+// of text. Like <<- itself, it leaves lines that are only whitespace untouched.
+func trimIndent(text string, n int) string {
+	if n == 0 {
+		return text
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" && strings.HasSuffix(line, "\n") {
+			continue
+		}
+		for j := 0; j < n && line != "" && unicode.IsSpace(rune(line[0])); j++ {
+			line = line[1:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "")
+}
+
+// tagAt returns the index of the tag that starts at the given byte, or -1
+func tagAt(tags []templateTag, start int) int {
+	i := sort.Search(len(tags), func(i int) bool {
+		return tags[i].rng.Start.Byte >= start
+	})
+	if i < len(tags) && tags[i].rng.Start.Byte == start {
+		return i
+	}
+	return -1
+}
+
+// matchingTags returns the indexes of the else and end tags that belong to the
+// if or for directive at tags[open], with -1 for a missing else
+func matchingTags(tags []templateTag, open int) (int, int) {
+	elseTag, depth := -1, 0
+	for i := open + 1; i < len(tags); i++ {
+		if !tags[i].directive {
+			continue
+		}
+		switch tags[i].keyword {
+		case "if", "for":
+			depth++
+		case "endif", "endfor":
+			if depth == 0 {
+				return elseTag, i
+			}
+			depth--
+		case "else":
+			if depth == 0 {
+				elseTag = i
+			}
+		}
+	}
+	return elseTag, -1
+}
+
+// templateParts converts the parts of a template and rebuilds the if and for
+// directives that hclsyntax lowers into conditionals and joins. Literal parts
+// keep their source text, as the strip markers are recorded on the directives.
+func (c *converter) templateParts(parts []hclsyntax.Expression, src *templateSource) ([]types.Expression, error) {
+	result := make([]types.Expression, 0, len(parts))
+	for _, part := range parts {
+		switch p := part.(type) {
+		case *hclsyntax.LiteralValueExpr:
+			// Directives without content get an empty literal that isn't in the source
+			if p.SrcRange.Empty() {
+				continue
+			}
+			result = append(result, &types.LiteralValue{
+				Value:     c.literalText(p, src),
+				ValueType: "string",
+				ExprRange: p.Range(),
+			})
+			continue
+		case *hclsyntax.ConditionalExpr:
+			if open := tagAt(src.tags, p.SrcRange.Start.Byte); open >= 0 && src.tags[open].keyword == "if" {
+				directive, err := c.templateIf(p, src, open)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, directive)
+				continue
+			}
+		case *hclsyntax.TemplateJoinExpr:
+			if forExpr, ok := p.Tuple.(*hclsyntax.ForExpr); ok {
+				if open := tagAt(src.tags, forExpr.OpenRange.Start.Byte); open >= 0 && src.tags[open].keyword == "for" {
+					directive, err := c.templateFor(forExpr, src, open)
+					if err != nil {
+						return nil, err
+					}
+					result = append(result, directive)
+					continue
+				}
+			}
+		}
+
+		converted, err := c.convertExpression(part)
 		if err != nil {
 			return nil, err
 		}
-		result[i] = converted
+		result = append(result, converted)
 	}
 	return result, nil
 }
 
-func convertLiteralValue(val cty.Value, rng hcl.Range) (*types.LiteralValue, error) {
-	var value interface{}
-	var valueType string
+func (c *converter) templateIf(e *hclsyntax.ConditionalExpr, src *templateSource, open int) (*types.TemplateIfDirective, error) {
+	elseTag, endTag := matchingTags(src.tags, open)
+	if endTag < 0 {
+		return nil, fmt.Errorf("missing endif for the if directive at %s", src.tags[open].rng)
+	}
+
+	condition, err := c.convertExpression(e.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert template condition: %w", err)
+	}
+	directive := &types.TemplateIfDirective{
+		Condition: condition,
+		Else:      elseTag >= 0,
+		Strip:     src.tags[open].strip,
+		EndStrip:  src.tags[endTag].strip,
+		ExprRange: e.Range(),
+	}
+
+	if directive.TrueExpr, err = c.templateBranch(e.TrueResult, src); err != nil {
+		return nil, err
+	}
+	if directive.Else {
+		directive.ElseStrip = src.tags[elseTag].strip
+		if directive.FalseExpr, err = c.templateBranch(e.FalseResult, src); err != nil {
+			return nil, err
+		}
+	}
+	return directive, nil
+}
 
+func (c *converter) templateFor(e *hclsyntax.ForExpr, src *templateSource, open int) (*types.TemplateForDirective, error) {
+	_, endTag := matchingTags(src.tags, open)
+	if endTag < 0 {
+		return nil, fmt.Errorf("missing endfor for the for directive at %s", src.tags[open].rng)
+	}
+
+	collection, err := c.convertExpression(e.CollExpr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert template collection: %w", err)
+	}
+	content, err := c.templateBranch(e.ValExpr, src)
+	if err != nil {
+		return nil, err
+	}
+	return &types.TemplateForDirective{
+		KeyVar:    e.KeyVar,
+		ValueVar:  e.ValVar,
+		CollExpr:  collection,
+		Content:   content,
+		Strip:     src.tags[open].strip,
+		EndStrip:  src.tags[endTag].strip,
+		ExprRange: e.Range(),
+	}, nil
+}
+
+// templateBranch converts the body of a directive, which hclsyntax wraps in a template
+func (c *converter) templateBranch(expr hclsyntax.Expression, src *templateSource) ([]types.Expression, error) {
+	if template, ok := expr.(*hclsyntax.TemplateExpr); ok {
+		return c.templateParts(template.Parts, src)
+	}
+	return c.templateParts([]hclsyntax.Expression{expr}, src)
+}
+
+// literalText returns the text of a template literal as written in the source.
+// Whitespace removed by the strip markers of an adjacent interpolation stays
+// removed, since only directives record their strip markers.
+func (c *converter) literalText(lit *hclsyntax.LiteralValueExpr, src *templateSource) string {
+	var text strings.Builder
+	for _, token := range c.tokensIn(lit.SrcRange) {
+		if token.Type != hclsyntax.TokenQuotedLit && token.Type != hclsyntax.TokenStringLit {
+			continue
+		}
+		value, diags := hclsyntax.ParseStringLiteralToken(token)
+		if diags.HasErrors() {
+			return lit.Val.AsString()
+		}
+		if token.Range.Start.Column == 1 {
+			value = trimIndent(value, src.indent)
+		}
+		text.WriteString(value)
+	}
+	if text.Len() == 0 {
+		return lit.Val.AsString()
+	}
+
+	value := text.String()
+	for _, tag := range src.tags {
+		if tag.directive {
+			continue
+		}
+		if tag.strip.Right && tag.rng.End.Byte == lit.SrcRange.Start.Byte {
+			value = strings.TrimLeftFunc(value, unicode.IsSpace)
+		}
+		if tag.strip.Left && tag.rng.Start.Byte == lit.SrcRange.End.Byte {
+			value = strings.TrimRightFunc(value, unicode.IsSpace)
+		}
+	}
+	return value
+}
+
+// convertLiteralValue converts a value written literally in the source
+func convertLiteralValue(val cty.Value, rng hcl.Range) *types.LiteralValue {
+	if val.IsNull() {
+		return &types.LiteralValue{
+			Value:     nil,
+			ValueType: "null",
+			ExprRange: rng,
+		}
+	}
+
+	var value interface{}
 	switch {
 	case val.Type() == cty.String:
 		value = val.AsString()
-		valueType = "string"
+	case val.Type() == cty.Bool:
+		value = val.True()
 	case val.Type() == cty.Number:
 		bf := val.AsBigFloat()
-		if f64, acc := bf.Float64(); acc == 0 {
-			value = f64
-			valueType = "number"
+		if bf.IsInt() {
+			i, _ := bf.Int64()
+			value = i
 		} else {
-			value = bf.String()
-			valueType = "number"
+			f, _ := bf.Float64()
+			value = f
 		}
-	case val.Type() == cty.Bool:
-		value = val.True()
-		valueType = "bool"
-	case val.IsNull():
-		value = nil
-		valueType = "null"
 	default:
-		return nil, fmt.Errorf("unsupported literal value type: %s", val.Type().GoString())
+		value = val.GoString()
 	}
 
 	return &types.LiteralValue{
 		Value:     value,
-		ValueType: valueType,
+		ValueType: val.Type().FriendlyName(),
 		ExprRange: rng,
-	}, nil
-}
-
-func convertRange(r hcl.Range) sitter.Range {
-	return sitter.Range{
-		StartPoint: sitter.Point{
-			Row:    uint32(r.Start.Line - 1),
-			Column: uint32(r.Start.Column - 1),
-		},
-		EndPoint: sitter.Point{
-			Row:    uint32(r.End.Line - 1),
-			Column: uint32(r.End.Column - 1),
-		},
-	}
-}
-
-// getOperationSymbol returns the string representation of an operation
-func getOperationSymbol(op *hclsyntax.Operation) string {
-	switch op {
-	// Arithmetic
-	case hclsyntax.OpAdd:
-		return "+"
-	case hclsyntax.OpSubtract:
-		return "-"
-	case hclsyntax.OpMultiply:
-		return "*"
-	case hclsyntax.OpDivide:
-		return "/"
-	case hclsyntax.OpModulo:
-		return "%"
-	case hclsyntax.OpNegate:
-		return "-"
-
-	// Logical
-	case hclsyntax.OpLogicalAnd:
-		return "&&"
-	case hclsyntax.OpLogicalOr:
-		return "||"
-	case hclsyntax.OpLogicalNot:
-		return "!"
-
-	// Comparison
-	case hclsyntax.OpEqual:
-		return "=="
-	case hclsyntax.OpNotEqual:
-		return "!="
-	case hclsyntax.OpGreaterThan:
-		return ">"
-	case hclsyntax.OpGreaterThanOrEqual:
-		return ">="
-	case hclsyntax.OpLessThan:
-		return "<"
-	case hclsyntax.OpLessThanOrEqual:
-		return "<="
-
-	default:
-		return "unknown"
 	}
 }
 
-func convertTraversal(traversal []hcl.Traverser) []string {
-	parts := make([]string, len(traversal))
+// convertTraversal converts the attribute and index steps of a traversal
+func convertTraversal(traversal hcl.Traversal) []types.TraversalElem {
+	elems := make([]types.TraversalElem, len(traversal))
 	for i, traverser := range traversal {
 		switch t := traverser.(type) {
-		case hcl.TraverseRoot:
-			parts[i] = t.Name
 		case hcl.TraverseAttr:
-			parts[i] = t.Name
-		case hcl.TraverseIndex:
-			// Convert the index value to a string
-			switch {
-			case t.Key.Type() == cty.Number:
-				bf := t.Key.AsBigFloat()
-				if bf.IsInt() {
-					i, _ := bf.Int64()
-					parts[i] = fmt.Sprintf("[%d]", i)
-				} else {
-					f, _ := bf.Float64()
-					parts[i] = fmt.Sprintf("[%f]", f)
-				}
-			case t.Key.Type() == cty.String:
-				parts[i] = fmt.Sprintf("[%q]", t.Key.AsString())
-			default:
-				parts[i] = fmt.Sprintf("[%s]", t.Key.GoString())
+			elems[i] = types.TraversalElem{
+				Type: "attr",
+				Name: t.Name,
 			}
-		}
-	}
-	return parts
-}
-
-func convertTraversalParts(traversal hcl.Traversal) []string {
-	parts := make([]string, len(traversal))
-	for i, traverser := range traversal {
-		switch t := traverser.(type) {
-		case hcl.TraverseRoot:
-			parts[i] = t.Name
-		case hcl.TraverseAttr:
-			parts[i] = t.Name
 		case hcl.TraverseIndex:
-			// Convert the index value to a string
-			switch {
-			case t.Key.Type() == cty.Number:
-				bf := t.Key.AsBigFloat()
-				if bf.IsInt() {
-					i, _ := bf.Int64()
-					parts[i] = fmt.Sprintf("[%d]", i)
-				} else {
-					f, _ := bf.Float64()
-					parts[i] = fmt.Sprintf("[%f]", f)
-				}
-			case t.Key.Type() == cty.String:
-				parts[i] = fmt.Sprintf("[%q]", t.Key.AsString())
-			default:
-				parts[i] = fmt.Sprintf("[%s]", t.Key.GoString())
+			elems[i] = types.TraversalElem{
+				Type:  "index",
+				Index: convertLiteralValue(t.Key, t.SrcRange),
 			}
 		}
 	}
-	return parts
-}
-
-// isReferenceExpr checks if a traversal should be treated as a reference expression
-func isReferenceExpr(parts []string) bool {
-	if len(parts) == 0 {
-		return false
-	}
-
-	// If it's a single part, it's a reference if it's a simple identifier
-	if len(parts) == 1 {
-		// Check if it's a simple identifier (not an index)
-		return !strings.HasPrefix(parts[0], "[")
-	}
-
-	// If it's a traversal with multiple parts, it's a reference if:
-	// 1. The first part is a simple identifier (not an index)
-	// 2. The subsequent parts are either simple identifiers or indices
-	return !strings.HasPrefix(parts[0], "[")
+	return elems
 }
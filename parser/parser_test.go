@@ -1,11 +1,15 @@
 package parser
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/vahid-haghighat/terralint/parser/types"
 )
 
@@ -26,6 +30,30 @@ func TestParser(t *testing.T) {
 			Description: "Simple Terraform file with basic constructs",
 			Expected:    createSimpleTerraformExpected(),
 		},
+		{
+			Name:        "Traversals",
+			FilePath:    "test_files/traversals_test.tf",
+			Description: "Splats, indexes and traversals of arbitrary expressions",
+			Expected:    createTraversalsExpected(),
+		},
+		{
+			Name:        "Template Directives",
+			FilePath:    "test_files/template_directives_test.tf",
+			Description: "Templates with interpolations, escapes and for/if directives",
+			Expected:    createTemplateDirectivesExpected(),
+		},
+		{
+			Name:        "Comments",
+			FilePath:    "test_files/comments_test.tf",
+			Description: "Leading, trailing and dangling comments on bodies, tuples and objects",
+			Expected:    createCommentsExpected(),
+		},
+		{
+			Name:        "Directives",
+			FilePath:    "test_files/directives_test.tf",
+			Description: "Ignore and format directives on lines of their own",
+			Expected:    createDirectivesExpected(),
+		},
 		{
 			Name:        "Complex Module",
 			FilePath:    "test_files/complex_terraform_split/01_complex_module.tf",
@@ -138,6 +166,13 @@ func compareStructures(t *testing.T, expected, actual types.Body) {
 			return
 		}
 		compareAttributes(t, exp, act)
+	case *types.FormatDirective:
+		act, ok := actual.(*types.FormatDirective)
+		if !ok {
+			t.Errorf("Type assertion failed: expected *types.FormatDirective, got %T", actual)
+			return
+		}
+		compareDirectives(t, exp, act)
 	default:
 		t.Errorf("Unsupported type for comparison: %T", expected)
 	}
@@ -160,6 +195,42 @@ func compareRoots(t *testing.T, expected, actual *types.Root) {
 		}
 		compareStructures(t, expChild, actual.Children[i])
 	}
+
+	compareComments(t, "root", expected.Comments, actual.Comments)
+}
+
+// compareComments compares the text of the comments attached to a node
+func compareComments(t *testing.T, node string, expected, actual types.Comments) {
+	lists := []struct {
+		kind             string
+		expected, actual []types.Comment
+	}{
+		{"leading", expected.Leading, actual.Leading},
+		{"trailing", expected.Trailing, actual.Trailing},
+		{"dangling", expected.Dangling, actual.Dangling},
+	}
+	for _, list := range lists {
+		if len(list.expected) != len(list.actual) {
+			t.Errorf("%s comments count mismatch for %s: expected %v, got %v", list.kind, node, list.expected, list.actual)
+			continue
+		}
+		for i := range list.expected {
+			if list.expected[i].Text != list.actual[i].Text {
+				t.Errorf("%s comment mismatch for %s: expected %q, got %q", list.kind, node, list.expected[i].Text, list.actual[i].Text)
+			}
+		}
+	}
+}
+
+// compareDirectives compares two FormatDirective structures
+func compareDirectives(t *testing.T, expected, actual *types.FormatDirective) {
+	if expected.DirectiveType != actual.DirectiveType {
+		t.Errorf("Directive type mismatch: expected %s, got %s", expected.DirectiveType, actual.DirectiveType)
+	}
+	if !reflect.DeepEqual(expected.Parameters, actual.Parameters) {
+		t.Errorf("Directive %s parameters mismatch: expected %v, got %v", expected.DirectiveType, expected.Parameters, actual.Parameters)
+	}
+	compareComments(t, "directive "+expected.DirectiveType, expected.Comments, actual.Comments)
 }
 
 // compareBlocks compares two Block structures
@@ -169,17 +240,7 @@ func compareBlocks(t *testing.T, expected, actual *types.Block) {
 		t.Errorf("Block type mismatch: expected %s, got %s", expected.Type, actual.Type)
 	}
 
-	// Check if the block comment matches
-	if expected.BlockComment != actual.BlockComment {
-		t.Errorf("Block comment mismatch for block %s: expected %q, got %q",
-			expected.Type, expected.BlockComment, actual.BlockComment)
-	}
-
-	// Check if the inline comment matches
-	if expected.InlineComment != actual.InlineComment {
-		t.Errorf("Inline comment mismatch for block %s: expected %q, got %q",
-			expected.Type, expected.InlineComment, actual.InlineComment)
-	}
+	compareComments(t, "block "+expected.Type, expected.Comments, actual.Comments)
 
 	// Check if the labels match
 	// Special case: if both are empty (nil or empty slice), consider them equal
@@ -236,17 +297,7 @@ func compareAttributes(t *testing.T, expected, actual *types.Attribute) {
 		t.Errorf("Attribute name mismatch: expected %s, got %s", expected.Name, actual.Name)
 	}
 
-	// Check if the block comment matches
-	if expected.BlockComment != actual.BlockComment {
-		t.Errorf("Block comment mismatch for attribute %s: expected %q, got %q",
-			expected.Name, expected.BlockComment, actual.BlockComment)
-	}
-
-	// Check if the inline comment matches
-	if expected.InlineComment != actual.InlineComment {
-		t.Errorf("Inline comment mismatch for attribute %s: expected %q, got %q",
-			expected.Name, expected.InlineComment, actual.InlineComment)
-	}
+	compareComments(t, "attribute "+expected.Name, expected.Comments, actual.Comments)
 
 	// Compare the attribute values
 	compareExpressions(t, expected.Value, actual.Value)
@@ -327,18 +378,11 @@ func compareExpressions(t *testing.T, expected, actual types.Expression) {
 				t.Errorf("Missing object item at index %d in actual", i)
 				continue
 			}
-			// Compare comments for object items
-			if expItem.BlockComment != act.Items[i].BlockComment {
-				t.Errorf("Object item block comment mismatch at index %d: expected %q, got %q",
-					i, expItem.BlockComment, act.Items[i].BlockComment)
-			}
-			if expItem.InlineComment != act.Items[i].InlineComment {
-				t.Errorf("Object item inline comment mismatch at index %d: expected %q, got %q",
-					i, expItem.InlineComment, act.Items[i].InlineComment)
-			}
+			compareComments(t, fmt.Sprintf("object item %d", i), expItem.Comments, act.Items[i].Comments)
 			compareExpressions(t, expItem.Key, act.Items[i].Key)
 			compareExpressions(t, expItem.Value, act.Items[i].Value)
 		}
+		compareComments(t, "object", exp.Comments, act.Comments)
 	case *types.ArrayExpr:
 		act, ok := actual.(*types.ArrayExpr)
 		if !ok {
@@ -380,6 +424,14 @@ func compareExpressions(t *testing.T, expected, actual types.Expression) {
 			}
 			compareExpressions(t, expItem, act.Items[i])
 		}
+		if len(exp.ItemComments) != len(act.ItemComments) {
+			t.Errorf("Array item comments mismatch: expected %d, got %d", len(exp.ItemComments), len(act.ItemComments))
+		} else {
+			for i := range exp.ItemComments {
+				compareComments(t, fmt.Sprintf("array item %d", i), exp.ItemComments[i], act.ItemComments[i])
+			}
+		}
+		compareComments(t, "array", exp.Comments, act.Comments)
 	case *types.ReferenceExpr:
 		act, ok := actual.(*types.ReferenceExpr)
 		if !ok {
@@ -390,10 +442,82 @@ func compareExpressions(t *testing.T, expected, actual types.Expression) {
 		if !reflect.DeepEqual(exp.Parts, act.Parts) {
 			t.Errorf("Reference parts mismatch: expected %v, got %v", exp.Parts, act.Parts)
 		}
+	case *types.SplatExpr:
+		act := actual.(*types.SplatExpr)
+		if exp.AttrOnly != act.AttrOnly {
+			t.Errorf("Splat form mismatch: expected attribute-only %v, got %v", exp.AttrOnly, act.AttrOnly)
+		}
+		compareExpressions(t, exp.Source, act.Source)
+		compareExpressions(t, exp.Each, act.Each)
+	case *types.RelativeTraversalExpr:
+		act := actual.(*types.RelativeTraversalExpr)
+		if len(exp.Traversal) != len(act.Traversal) {
+			t.Errorf("Traversal length mismatch: expected %d, got %d", len(exp.Traversal), len(act.Traversal))
+			return
+		}
+		for i, expElem := range exp.Traversal {
+			actElem := act.Traversal[i]
+			if expElem.Type != actElem.Type || expElem.Name != actElem.Name {
+				t.Errorf("Traversal element mismatch at index %d: expected %s %q, got %s %q",
+					i, expElem.Type, expElem.Name, actElem.Type, actElem.Name)
+			}
+			compareExpressions(t, expElem.Index, actElem.Index)
+		}
+		compareExpressions(t, exp.Source, act.Source)
+	case *types.IndexExpr:
+		act := actual.(*types.IndexExpr)
+		compareExpressions(t, exp.Collection, act.Collection)
+		compareExpressions(t, exp.Key, act.Key)
+	case *types.ParenExpr:
+		compareExpressions(t, exp.Expression, actual.(*types.ParenExpr).Expression)
+	case *types.TemplateExpr:
+		compareExpressionLists(t, "Template parts", exp.Parts, actual.(*types.TemplateExpr).Parts)
+	case *types.HeredocExpr:
+		act := actual.(*types.HeredocExpr)
+		if exp.Marker != act.Marker || exp.Indented != act.Indented {
+			t.Errorf("Heredoc opener mismatch: expected %q (indented %v), got %q (indented %v)",
+				exp.Marker, exp.Indented, act.Marker, act.Indented)
+		}
+		if exp.Content != act.Content {
+			t.Errorf("Heredoc content mismatch:\nexpected:\n%s\ngot:\n%s", exp.Content, act.Content)
+		}
+		if exp.Parts != nil {
+			compareExpressionLists(t, "Heredoc parts", exp.Parts, act.Parts)
+		}
+	case *types.TemplateIfDirective:
+		act := actual.(*types.TemplateIfDirective)
+		if exp.Else != act.Else || exp.Strip != act.Strip || exp.ElseStrip != act.ElseStrip || exp.EndStrip != act.EndStrip {
+			t.Errorf("If directive tags mismatch: expected else %v, strips %v %v %v, got else %v, strips %v %v %v",
+				exp.Else, exp.Strip, exp.ElseStrip, exp.EndStrip, act.Else, act.Strip, act.ElseStrip, act.EndStrip)
+		}
+		compareExpressions(t, exp.Condition, act.Condition)
+		compareExpressionLists(t, "If directive true branch", exp.TrueExpr, act.TrueExpr)
+		compareExpressionLists(t, "If directive false branch", exp.FalseExpr, act.FalseExpr)
+	case *types.TemplateForDirective:
+		act := actual.(*types.TemplateForDirective)
+		if exp.KeyVar != act.KeyVar || exp.ValueVar != act.ValueVar {
+			t.Errorf("For directive variables mismatch: expected %q, %q, got %q, %q", exp.KeyVar, exp.ValueVar, act.KeyVar, act.ValueVar)
+		}
+		if exp.Strip != act.Strip || exp.EndStrip != act.EndStrip {
+			t.Errorf("For directive strips mismatch: expected %v %v, got %v %v", exp.Strip, exp.EndStrip, act.Strip, act.EndStrip)
+		}
+		compareExpressions(t, exp.CollExpr, act.CollExpr)
+		compareExpressionLists(t, "For directive content", exp.Content, act.Content)
 	}
 	// Add more expression type comparisons as needed
 }
 
+// compareExpressionLists compares two lists of expressions item by item
+func compareExpressionLists(t *testing.T, name string, expected, actual []types.Expression) {
+	if len(expected) != len(actual) {
+		t.Errorf("%s count mismatch: expected %d, got %d", name, len(expected), len(actual))
+		return
+	}
+	for i := range expected {
+		compareExpressions(t, expected[i], actual[i])
+	}
+}
+
 // Helper function to compare string slices
 func compareStringSlices(a, b []string) bool {
 	if len(a) != len(b) {
@@ -406,3 +530,448 @@ func compareStringSlices(a, b []string) bool {
 	}
 	return true
 }
+
+func TestParseSource(t *testing.T) {
+	content, err := os.ReadFile("test_files/simple_test.tf")
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+
+	// The filename doesn't exist on disk, so every comment has to come from content
+	root, err := ParseSource(content, "in-memory/simple_test.tf")
+	if err != nil {
+		t.Fatalf("Failed to parse in-memory source: %v", err)
+	}
+
+	compareStructures(t, createSimpleTerraformExpected(), root)
+}
+
+func TestParseFS(t *testing.T) {
+	content, err := os.ReadFile("test_files/simple_test.tf")
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+
+	fsys := fstest.MapFS{
+		"modules/simple_test.tf": &fstest.MapFile{Data: content},
+	}
+
+	root, err := ParseFS(fsys, "modules/simple_test.tf")
+	if err != nil {
+		t.Fatalf("Failed to parse from fs.FS: %v", err)
+	}
+
+	compareStructures(t, createSimpleTerraformExpected(), root)
+
+	if _, err := ParseFS(fsys, "modules/missing.tf"); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
+
+func TestParseModule(t *testing.T) {
+	module, err := ParseModule("test_files/modules_test")
+	if err != nil {
+		t.Fatalf("Failed to parse module: %v", err)
+	}
+
+	counts := map[string][]int{
+		"files":        {len(module.Files), 2},
+		"variables":    {len(module.Variables), 21},
+		"locals":       {len(module.Locals), 4},
+		"outputs":      {len(module.Outputs), 8},
+		"resources":    {len(module.Resources), 7},
+		"module calls": {len(module.ModuleCalls), 2},
+		"providers":    {len(module.Providers), 1},
+		"terraform":    {len(module.Terraform), 1},
+	}
+	for name, count := range counts {
+		if count[0] != count[1] {
+			t.Errorf("Expected %d %s, got %d", count[1], name, count[0])
+		}
+	}
+
+	region := module.Lookup("var.region")
+	if len(region) != 1 || region[0].File != filepath.Join("test_files/modules_test", "variables.tf") || region[0].Range.Start.Line != 3 {
+		t.Errorf("Unexpected declaration of var.region: %+v", region)
+	}
+	if app := module.Lookup("aws_instance.app"); len(app) != 1 || app[0].Type != "aws_instance" || app[0].Name != "app" {
+		t.Errorf("Unexpected declaration of aws_instance.app: %+v", app)
+	}
+}
+
+func TestParseModuleFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"network/main.tf": &fstest.MapFile{Data: []byte(`provider "aws" {}
+
+provider "aws" {
+  alias = "east"
+}
+
+data "aws_ami" "ubuntu" {}
+
+locals {
+  name = "app"
+  tags = {}
+}
+`)},
+		"network/variables.tf":     &fstest.MapFile{Data: []byte("variable \"name\" {}\nvariable \"name\" {}\n")},
+		"network/main_override.tf": &fstest.MapFile{Data: []byte("variable \"name\" {\n  default = \"x\"\n}\n")},
+		"network/.draft.tf":        &fstest.MapFile{Data: []byte("not terraform {")},
+		"network/README.md":        &fstest.MapFile{Data: []byte("# Network")},
+		"network/nested/main.tf":   &fstest.MapFile{Data: []byte("variable \"nested\" {}\n")},
+	}
+
+	module, err := ParseModuleFS(fsys, "network")
+	if err != nil {
+		t.Fatalf("Failed to parse module: %v", err)
+	}
+
+	var files []string
+	for _, file := range module.Files {
+		files = append(files, file.Path)
+	}
+	if expected := []string{"network/main.tf", "network/main_override.tf", "network/variables.tf"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected files %v, got %v", expected, files)
+	}
+	if !module.File("network/main_override.tf").Override {
+		t.Errorf("Expected main_override.tf to be an override file")
+	}
+
+	var addresses []string
+	for _, declaration := range module.Declarations() {
+		addresses = append(addresses, fmt.Sprintf("%s %s:%d", declaration.Address(), declaration.File, declaration.Range.Start.Line))
+	}
+	expected := []string{
+		"provider.aws network/main.tf:1",
+		"provider.aws.east network/main.tf:3",
+		"data.aws_ami.ubuntu network/main.tf:7",
+		"local.name network/main.tf:10",
+		"local.tags network/main.tf:11",
+		"var.name network/variables.tf:1",
+		"var.name network/variables.tf:2",
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("Declarations mismatch:\nexpected: %v\ngot:      %v", expected, addresses)
+	}
+	if len(module.Lookup("var.name")) != 2 {
+		t.Errorf("Expected both declarations of var.name")
+	}
+
+	fsys["network/broken.tf"] = &fstest.MapFile{Data: []byte("resource \"a\" \"b\" {")}
+	if _, err := ParseModuleFS(fsys, "network"); err == nil {
+		t.Errorf("Expected an error for a file that doesn't parse")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	fsys := fstest.MapFS{
+		"variables.tf": &fstest.MapFile{Data: []byte(`variable "names" {
+  type = map(object({ size = number }))
+}
+`)},
+		"main.tf": &fstest.MapFile{Data: []byte(`provider "aws" {
+  alias = "east"
+}
+
+locals {
+  upper = { for k, v in var.names : upper(k) => v.size if v != null }
+  text  = "%{for n in var.names}${n}%{endfor}"
+}
+
+resource "aws_instance" "app" {
+  for_each = var.names
+  provider = aws.east
+  name     = "${each.key}-${path.module}-${terraform.workspace}"
+  subnet   = module.vpc.subnet_ids[0]
+  ami      = data.aws_ami.missing.id
+  tags     = { Name = local.upper }
+
+  dynamic "disk" {
+    for_each = local.text
+    content {
+      size = disk.value
+    }
+  }
+
+  provisioner "local-exec" {
+    command = self.id
+  }
+
+  lifecycle {
+    ignore_changes = [tags]
+  }
+}
+
+output "ids" {
+  value = [for a in aws_instance.app : a.id]
+}
+
+output "broken" {
+  value = [count.index, var.missing, a, self.id, each.other, path.elsewhere]
+}
+`)},
+	}
+
+	module, err := ParseModuleFS(fsys, ".")
+	if err != nil {
+		t.Fatalf("Failed to parse module: %v", err)
+	}
+
+	bindings := describeBindings(module)
+	expected := []string{
+		"main.tf:6 var.names: var.names",
+		"main.tf:6 k: iterator *types.ForMapExpr",
+		"main.tf:6 v.size: iterator *types.ForMapExpr",
+		"main.tf:6 v: iterator *types.ForMapExpr",
+		"main.tf:7 var.names: var.names",
+		"main.tf:7 n: iterator *types.TemplateForDirective",
+		"main.tf:11 var.names: var.names",
+		"main.tf:12 aws.east: provider.aws.east",
+		"main.tf:13 each.key: builtin",
+		"main.tf:13 path.module: builtin",
+		"main.tf:13 terraform.workspace: builtin",
+		`main.tf:14 module.vpc.subnet_ids: module "vpc" is not declared`,
+		`main.tf:15 data.aws_ami.missing.id: data source "aws_ami.missing" is not declared`,
+		"main.tf:16 local.upper: local.upper",
+		"main.tf:19 local.text: local.text",
+		"main.tf:21 disk.value: iterator *types.Block",
+		"main.tf:26 self.id: builtin",
+		"main.tf:35 aws_instance.app: aws_instance.app",
+		"main.tf:35 a.id: iterator *types.ForArrayExpr",
+		"main.tf:39 count.index: count can only be used in blocks with count",
+		`main.tf:39 var.missing: variable "missing" is not declared`,
+		`main.tf:39 a: unknown name "a"`,
+		"main.tf:39 self.id: self can only be used in provisioner, connection and postcondition blocks",
+		"main.tf:39 each.other: each can only be used in blocks with for_each",
+		`main.tf:39 path.elsewhere: path has no attribute "elsewhere"`,
+	}
+	if !reflect.DeepEqual(bindings, expected) {
+		t.Errorf("Bindings mismatch:\nexpected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(bindings, "\n"))
+	}
+}
+
+func TestResolveConfigurationAliases(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.tf": &fstest.MapFile{Data: []byte(`terraform {
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      configuration_aliases = [aws.west]
+    }
+  }
+}
+
+resource "aws_instance" "app" {
+  provider = aws.west
+}
+
+module "vpc" {
+  source = "./vpc"
+  providers = {
+    aws = aws.west
+  }
+}
+
+data "aws_ami" "ubuntu" {
+  provider = aws.east
+}
+`)},
+	}
+
+	module, err := ParseModuleFS(fsys, ".")
+	if err != nil {
+		t.Fatalf("Failed to parse module: %v", err)
+	}
+
+	aliases := module.Lookup("provider.aws.west")
+	if len(aliases) != 1 || aliases[0].Range.Start.Line != 5 || aliases[0].Attribute == nil || aliases[0].Attribute.Name != "aws" {
+		t.Fatalf("Expected the configuration alias to declare provider.aws.west, got %+v", aliases)
+	}
+
+	expected := []string{
+		"main.tf:11 aws.west: provider.aws.west",
+		"main.tf:17 aws.west: provider.aws.west",
+		`main.tf:22 aws.east: provider configuration "aws.east" is not declared`,
+	}
+	if bindings := describeBindings(module); !reflect.DeepEqual(bindings, expected) {
+		t.Errorf("Bindings mismatch:\nexpected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(bindings, "\n"))
+	}
+}
+
+// describeBindings describes every binding of a module as the file, line and
+// name of the reference followed by what it resolves to
+func describeBindings(module *types.Module) []string {
+	var bindings []string
+	for _, binding := range module.Resolve() {
+		bound := binding.Kind
+		switch binding.Kind {
+		case types.BindingDeclaration:
+			bound = binding.Declaration.Address()
+		case types.BindingIterator:
+			bound = "iterator " + fmt.Sprintf("%T", binding.Scope)
+		case types.BindingUnresolved:
+			bound = binding.Problem
+		}
+		bindings = append(bindings, fmt.Sprintf("%s:%d %s: %s", binding.File, binding.Reference.Range().Start.Line, strings.Join(binding.Reference.Parts, "."), bound))
+	}
+	return bindings
+}
+
+func BenchmarkParseComplexTerraform(b *testing.B) {
+	paths, err := filepath.Glob("test_files/complex_terraform_split/*.tf")
+	if err != nil || len(paths) == 0 {
+		b.Fatalf("Failed to find benchmark files: %v", err)
+	}
+
+	var sources [][]byte
+	var size int64
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatalf("Failed to read %s: %v", path, err)
+		}
+		sources = append(sources, content)
+		size += int64(len(content))
+	}
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for j, content := range sources {
+			if _, err := ParseSource(content, paths[j]); err != nil {
+				b.Fatalf("Failed to parse %s: %v", paths[j], err)
+			}
+		}
+	}
+}
+
+// TestExpressionRanges checks that every node of the complex fixtures points at its source
+func TestExpressionRanges(t *testing.T) {
+	paths, err := filepath.Glob("test_files/complex_terraform_split/*.tf")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("Failed to find test files: %v", err)
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			root, err := ParseTerraformFile(path)
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", path, err)
+			}
+			types.Inspect(root, func(node types.Node) bool {
+				switch n := node.(type) {
+				case *types.Block:
+					checkRange(t, n, n.Range)
+				case *types.Attribute:
+					checkRange(t, n, n.Range)
+				case types.Expression:
+					checkRange(t, n, n.Range())
+				}
+				return true
+			})
+		})
+	}
+}
+
+func checkRange(t *testing.T, node interface{}, r hcl.Range) {
+	if r.Filename == "" || r.Start.Line == 0 || r.Empty() {
+		t.Errorf("%T has an empty range: %#v", node, r)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	source := "locals {\n  a = merge({ k = var.m }, [for x in var.xs : x])\n  b = \"${var.p}-%{if var.c}y%{endif}\"\n}\n"
+	root, err := ParseSource([]byte(source), "main.tf")
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	var visited []string
+	depth := 0
+	types.Inspect(root, func(node types.Node) bool {
+		switch n := node.(type) {
+		case nil:
+			depth--
+			return false
+		case types.Body:
+			visited = append(visited, fmt.Sprintf("%d:%s", depth, n.BodyType()))
+		case types.Expression:
+			visited = append(visited, fmt.Sprintf("%d:%s", depth, n.ExpressionType()))
+		}
+		depth++
+		return true
+	})
+
+	expected := []string{
+		"0:root",
+		"1:block",
+		"2:attribute",
+		"3:function_call",
+		"4:object",
+		"5:object_item",
+		"6:reference",
+		"6:reference",
+		"4:for_array",
+		"5:reference",
+		"5:reference",
+		"2:attribute",
+		"3:template",
+		"4:reference",
+		"4:literal",
+		"4:template_if",
+		"5:reference",
+		"5:literal",
+	}
+	if !reflect.DeepEqual(expected, visited) {
+		t.Errorf("Visited nodes mismatch:\nexpected: %v\ngot: %v", expected, visited)
+	}
+	if depth != 0 {
+		t.Errorf("Every visited node should be followed by a nil visit, depth ended at %d", depth)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	source := "locals {\n  a = var.old\n  b = [var.old, 1, 2]\n  c = 3\n}\n"
+	root, err := ParseSource([]byte(source), "main.tf")
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	result := types.Rewrite(root, func(node types.Node) types.Node {
+		switch n := node.(type) {
+		case *types.ReferenceExpr:
+			if reflect.DeepEqual(n.Parts, []string{"var", "old"}) {
+				return &types.ReferenceExpr{Parts: []string{"var", "new"}, ExprRange: n.ExprRange}
+			}
+		case *types.LiteralValue:
+			if n.Value == int64(1) {
+				return nil
+			}
+		case *types.Attribute:
+			if n.Name == "c" {
+				return nil
+			}
+		}
+		return node
+	})
+
+	newRef := &types.ReferenceExpr{Parts: []string{"var", "new"}}
+	compareStructures(t, &types.Root{
+		Children: []types.Body{
+			&types.Block{
+				Type: "locals",
+				Children: []types.Body{
+					&types.Attribute{Name: "a", Value: newRef},
+					&types.Attribute{Name: "b", Value: &types.ArrayExpr{
+						Items: []types.Expression{
+							newRef,
+							&types.LiteralValue{Value: int64(2), ValueType: "number"},
+						},
+					}},
+				},
+			},
+		},
+	}, result.(types.Body))
+}
@@ -0,0 +1,27 @@
+/*
+ * Comments in every position the parser attaches them to
+ */
+
+# Leading comment of a block
+resource "aws_instance" "web" { # After the opening brace
+  // Leading comment of an attribute
+  ami = "ami-12345678" # Trailing comment of an attribute
+
+  security_groups = [ # After the opening bracket
+    "sg-1", # Trailing comment of an item
+    # Leading comment of an item
+    "sg-2",
+    # After the last item
+  ]
+
+  tags = {
+    # Leading comment of an object item
+    Name = "web" // Trailing comment of an object item
+    # After the last object item
+  }
+
+  # After the last attribute
+} # After the closing brace
+
+# At the end of the file
+/* Block comment at the end of the file */
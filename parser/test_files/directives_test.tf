@@ -0,0 +1,20 @@
+# terralint-ignore-file order
+
+# Reviewed with the network team
+# terralint-ignore format, order
+resource "aws_instance" "web" {
+  ami = "ami-12345678" # terralint-ignore format
+
+  # terralint:format off
+  matrix = [
+    1, 0,
+    0, 1,
+  ]
+  // terralint:format on
+
+  # terralint-ignored is not a directive
+  tags = {
+    # terralint-ignore format
+    Name = "web"
+  }
+}
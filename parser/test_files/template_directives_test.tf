@@ -22,6 +22,12 @@ locals {
   // Template with conditional expressions
   conditional_template = "Status: ${var.status == "active" ? "Active" : "Inactive"}"
   
+  // Template with inline directives
+  inline_if_template = "Hello, %{ if var.name != "" }${var.name}%{ else }stranger%{ endif }!"
+  
+  // Template with inline directives and strip markers
+  inline_for_template = "Items:%{ for i, item in var.items ~} ${i}=${item}%{~ endfor ~} done"
+  
   // Template with strip markers
   strip_markers_template = <<-EOT
     This is a template with strip markers.
@@ -65,6 +71,14 @@ locals {
     }
   EOT
   
+  // Heredoc with a custom marker
+  policy_template = <<POLICY
+{
+  "Resource": "${var.bucket_arn}/*",
+  "Escaped": "$${aws:username}"
+}
+POLICY
+  
   // Template with escaping
   escaped_template = "Escaped interpolation: $${var.name}, Escaped directive: %{if true}not processed%{endif}"
   
@@ -146,8 +160,6 @@ resource "aws_instance" "template_directives" {
     Environment = var.environment
     CreatedAt = timestamp()
     CreatedBy = "Terraform"
-    %{if var.include_owner~}
-    Owner = var.owner
-    %{endif~}
+    Owner = "%{if var.include_owner}${var.owner}%{else}unassigned%{endif}"
   }
 }
\ No newline at end of file
@@ -0,0 +1,33 @@
+// Traversals and splats in every form the HCL parser produces
+locals {
+  // Full splat with an attribute
+  instance_ids = aws_instance.web[*].id
+
+  // Full splat on its own
+  instances = aws_instance.web[*]
+
+  // Attribute-only splat
+  legacy_ids = aws_instance.web.*.id
+
+  // Full splat followed by an index
+  volume_ids = aws_instance.web[*].ebs_block_device[0].volume_id
+
+  // Literal index inside a reference
+  first_subnet = aws_subnet.private[0].id
+
+  // Expression index
+  current_subnet = aws_subnet.private[count.index].id
+
+  // Traversal of a function result
+  first_zone = sort(var.zones)[0]
+
+  // Traversal of a parenthesized expression
+  subnet_name = (var.public ? aws_subnet.public : aws_subnet.private).tags["Name"]
+
+  // Parenthesized and quoted object keys
+  tags = {
+    (var.tag_key) = "value"
+    "quoted"      = "value"
+    plain         = "value"
+  }
+}
@@ -4,6 +4,15 @@ import (
 	"github.com/vahid-haghighat/terralint/parser/types"
 )
 
+// comments builds the expected comments from their source text
+func comments(texts ...string) []types.Comment {
+	result := make([]types.Comment, len(texts))
+	for i, text := range texts {
+		result[i] = types.Comment{Text: text}
+	}
+	return result
+}
+
 // createSimpleTerraformExpected creates the expected structure for simple_test.tf
 func createSimpleTerraformExpected() types.Body {
 	return &types.Root{
@@ -52,12 +61,12 @@ func createSimpleTerraformExpected() types.Body {
 						},
 					},
 				},
-				BlockComment: "Simple Terraform file for testing the parser\nResource block",
+				Comments: types.Comments{Leading: comments("// Resource block")},
 			},
 			&types.Block{
-				Type:         "variable",
-				Labels:       []string{"region"},
-				BlockComment: "Variable block",
+				Type:     "variable",
+				Labels:   []string{"region"},
+				Comments: types.Comments{Leading: comments("// Variable block")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name: "description",
@@ -82,9 +91,9 @@ func createSimpleTerraformExpected() types.Body {
 				},
 			},
 			&types.Block{
-				Type:         "output",
-				Labels:       []string{"instance_id"},
-				BlockComment: "Output block",
+				Type:     "output",
+				Labels:   []string{"instance_id"},
+				Comments: types.Comments{Leading: comments("// Output block")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name: "description",
@@ -102,9 +111,9 @@ func createSimpleTerraformExpected() types.Body {
 				},
 			},
 			&types.Block{
-				Type:         "data",
-				Labels:       []string{"aws_ami", "ubuntu"},
-				BlockComment: "Data source block",
+				Type:     "data",
+				Labels:   []string{"aws_ami", "ubuntu"},
+				Comments: types.Comments{Leading: comments("// Data source block")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name: "most_recent",
@@ -169,14 +178,14 @@ func createSimpleTerraformExpected() types.Body {
 								},
 							},
 						},
-						InlineComment: "Canonical",
+						Comments: types.Comments{Trailing: comments("# Canonical")},
 					},
 				},
 			},
 			&types.Block{
-				Type:         "provider",
-				Labels:       []string{"aws"},
-				BlockComment: "Provider block",
+				Type:     "provider",
+				Labels:   []string{"aws"},
+				Comments: types.Comments{Leading: comments("// Provider block")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name: "region",
@@ -187,8 +196,8 @@ func createSimpleTerraformExpected() types.Body {
 				},
 			},
 			&types.Block{
-				Type:         "locals",
-				BlockComment: "Locals block",
+				Type:     "locals",
+				Comments: types.Comments{Leading: comments("// Locals block")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name: "common_tags",
@@ -227,9 +236,9 @@ func createSimpleTerraformExpected() types.Body {
 				},
 			},
 			&types.Block{
-				Type:         "module",
-				Labels:       []string{"vpc"},
-				BlockComment: "Module block",
+				Type:     "module",
+				Labels:   []string{"vpc"},
+				Comments: types.Comments{Leading: comments("// Module block")},
 				Children: []types.Body{
 					&types.Attribute{
 						Name: "source",
@@ -332,6 +341,581 @@ func createSimpleTerraformExpected() types.Body {
 				},
 			},
 		},
+		Comments: types.Comments{Leading: comments("// Simple Terraform file for testing the parser")},
+	}
+}
+
+// createTraversalsExpected creates the expected structure for traversals_test.tf
+func createTraversalsExpected() types.Body {
+	return &types.Root{
+		Children: []types.Body{
+			&types.Block{
+				Type:     "locals",
+				Comments: types.Comments{Leading: comments("// Traversals and splats in every form the HCL parser produces")},
+				Children: []types.Body{
+					&types.Attribute{
+						Name:     "instance_ids",
+						Comments: types.Comments{Leading: comments("// Full splat with an attribute")},
+						Value: &types.SplatExpr{
+							Source: &types.ReferenceExpr{Parts: []string{"aws_instance", "web"}},
+							Each: &types.RelativeTraversalExpr{
+								Source:    &types.AnonSymbolExpr{},
+								Traversal: []types.TraversalElem{{Type: "attr", Name: "id"}},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "instances",
+						Comments: types.Comments{Leading: comments("// Full splat on its own")},
+						Value: &types.SplatExpr{
+							Source: &types.ReferenceExpr{Parts: []string{"aws_instance", "web"}},
+							Each:   &types.AnonSymbolExpr{},
+						},
+					},
+					&types.Attribute{
+						Name:     "legacy_ids",
+						Comments: types.Comments{Leading: comments("// Attribute-only splat")},
+						Value: &types.SplatExpr{
+							Source: &types.ReferenceExpr{Parts: []string{"aws_instance", "web"}},
+							Each: &types.RelativeTraversalExpr{
+								Source:    &types.AnonSymbolExpr{},
+								Traversal: []types.TraversalElem{{Type: "attr", Name: "id"}},
+							},
+							AttrOnly: true,
+						},
+					},
+					&types.Attribute{
+						Name:     "volume_ids",
+						Comments: types.Comments{Leading: comments("// Full splat followed by an index")},
+						Value: &types.SplatExpr{
+							Source: &types.ReferenceExpr{Parts: []string{"aws_instance", "web"}},
+							Each: &types.RelativeTraversalExpr{
+								Source: &types.AnonSymbolExpr{},
+								Traversal: []types.TraversalElem{
+									{Type: "attr", Name: "ebs_block_device"},
+									{Type: "index", Index: &types.LiteralValue{Value: int64(0), ValueType: "number"}},
+									{Type: "attr", Name: "volume_id"},
+								},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "first_subnet",
+						Comments: types.Comments{Leading: comments("// Literal index inside a reference")},
+						Value: &types.RelativeTraversalExpr{
+							Source: &types.ReferenceExpr{Parts: []string{"aws_subnet", "private"}},
+							Traversal: []types.TraversalElem{
+								{Type: "index", Index: &types.LiteralValue{Value: int64(0), ValueType: "number"}},
+								{Type: "attr", Name: "id"},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "current_subnet",
+						Comments: types.Comments{Leading: comments("// Expression index")},
+						Value: &types.RelativeTraversalExpr{
+							Source: &types.IndexExpr{
+								Collection: &types.ReferenceExpr{Parts: []string{"aws_subnet", "private"}},
+								Key:        &types.ReferenceExpr{Parts: []string{"count", "index"}},
+							},
+							Traversal: []types.TraversalElem{{Type: "attr", Name: "id"}},
+						},
+					},
+					&types.Attribute{
+						Name:     "first_zone",
+						Comments: types.Comments{Leading: comments("// Traversal of a function result")},
+						Value: &types.RelativeTraversalExpr{
+							Source: &types.FunctionCallExpr{
+								Name: "sort",
+								Args: []types.Expression{
+									&types.ReferenceExpr{Parts: []string{"var", "zones"}},
+								},
+							},
+							Traversal: []types.TraversalElem{
+								{Type: "index", Index: &types.LiteralValue{Value: int64(0), ValueType: "number"}},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "subnet_name",
+						Comments: types.Comments{Leading: comments("// Traversal of a parenthesized expression")},
+						Value: &types.RelativeTraversalExpr{
+							Source: &types.ParenExpr{
+								Expression: &types.ConditionalExpr{
+									Condition: &types.ReferenceExpr{Parts: []string{"var", "public"}},
+									TrueExpr:  &types.ReferenceExpr{Parts: []string{"aws_subnet", "public"}},
+									FalseExpr: &types.ReferenceExpr{Parts: []string{"aws_subnet", "private"}},
+								},
+							},
+							Traversal: []types.TraversalElem{
+								{Type: "attr", Name: "tags"},
+								{Type: "index", Index: &types.LiteralValue{Value: "Name", ValueType: "string"}},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "tags",
+						Comments: types.Comments{Leading: comments("// Parenthesized and quoted object keys")},
+						Value: &types.ObjectExpr{
+							Items: []types.ObjectItem{
+								{
+									Key: &types.ParenExpr{
+										Expression: &types.ReferenceExpr{Parts: []string{"var", "tag_key"}},
+									},
+									Value: &types.LiteralValue{Value: "value", ValueType: "string"},
+								},
+								{
+									Key:   &types.LiteralValue{Value: "quoted", ValueType: "string"},
+									Value: &types.LiteralValue{Value: "value", ValueType: "string"},
+								},
+								{
+									Key:   &types.ReferenceExpr{Parts: []string{"plain"}},
+									Value: &types.LiteralValue{Value: "value", ValueType: "string"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// createTemplateDirectivesExpected creates the expected structure for template_directives_test.tf
+func createTemplateDirectivesExpected() types.Body {
+	str := func(value string) *types.LiteralValue {
+		return &types.LiteralValue{Value: value, ValueType: "string"}
+	}
+	ref := func(parts ...string) *types.ReferenceExpr {
+		return &types.ReferenceExpr{Parts: parts}
+	}
+
+	return &types.Root{
+		Children: []types.Body{
+			&types.Block{
+				Type: "locals",
+				Children: []types.Body{
+					&types.Attribute{
+						Name:     "complex_template",
+						Comments: types.Comments{Leading: comments("// Complex template with multiple interpolations")},
+						Value: &types.TemplateExpr{
+							Parts: []types.Expression{
+								str("Hello, "),
+								ref("var", "name"),
+								str("! Welcome to "),
+								ref("var", "environment"),
+								str(" environment. Your IP is "),
+								ref("var", "ip_address"),
+								str("."),
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "nested_template",
+						Comments: types.Comments{Leading: comments("// Template with nested expressions")},
+						Value: &types.TemplateExpr{
+							Parts: []types.Expression{
+								str("The result is: "),
+								&types.ConditionalExpr{
+									Condition: ref("var", "enable_feature"),
+									TrueExpr: &types.FunctionCallExpr{
+										Name: "upper",
+										Args: []types.Expression{ref("var", "feature_name")},
+									},
+									FalseExpr: str("Feature disabled"),
+								},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "function_template",
+						Comments: types.Comments{Leading: comments("// Template with function calls")},
+						Value: &types.TemplateExpr{
+							Parts: []types.Expression{
+								str("Timestamp: "),
+								&types.FunctionCallExpr{Name: "timestamp"},
+								str(", UUID: "),
+								&types.FunctionCallExpr{Name: "uuid"},
+								str(", Base64: "),
+								&types.FunctionCallExpr{
+									Name: "base64encode",
+									Args: []types.Expression{str("Hello")},
+								},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "math_template",
+						Comments: types.Comments{Leading: comments("// Template with math expressions")},
+						Value: &types.TemplateExpr{
+							Parts: []types.Expression{
+								str("The answer is "),
+								&types.BinaryExpr{},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "reference_template",
+						Comments: types.Comments{Leading: comments("// Template with references to other resources")},
+						Value: &types.TemplateExpr{
+							Parts: []types.Expression{
+								str("Instance ID: "),
+								ref("aws_instance", "example", "id"),
+								str(", Public IP: "),
+								ref("aws_instance", "example", "public_ip"),
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "for_template",
+						Comments: types.Comments{Leading: comments("// Template with for expressions")},
+						Value: &types.TemplateExpr{
+							Parts: []types.Expression{
+								str("Items: "),
+								&types.FunctionCallExpr{Name: "join"},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "conditional_template",
+						Comments: types.Comments{Leading: comments("// Template with conditional expressions")},
+						Value: &types.TemplateExpr{
+							Parts: []types.Expression{
+								str("Status: "),
+								&types.ConditionalExpr{
+									Condition: &types.BinaryExpr{},
+									TrueExpr:  str("Active"),
+									FalseExpr: str("Inactive"),
+								},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "inline_if_template",
+						Comments: types.Comments{Leading: comments("// Template with inline directives")},
+						Value: &types.TemplateExpr{
+							Parts: []types.Expression{
+								str("Hello, "),
+								&types.TemplateIfDirective{
+									Condition: &types.BinaryExpr{},
+									TrueExpr:  []types.Expression{ref("var", "name")},
+									FalseExpr: []types.Expression{str("stranger")},
+									Else:      true,
+								},
+								str("!"),
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "inline_for_template",
+						Comments: types.Comments{Leading: comments("// Template with inline directives and strip markers")},
+						Value: &types.TemplateExpr{
+							Parts: []types.Expression{
+								str("Items:"),
+								&types.TemplateForDirective{
+									KeyVar:   "i",
+									ValueVar: "item",
+									CollExpr: ref("var", "items"),
+									Content:  []types.Expression{str(" "), ref("i"), str("="), ref("item")},
+									Strip:    types.TemplateStrip{Right: true},
+									EndStrip: types.TemplateStrip{Left: true, Right: true},
+								},
+								str(" done"),
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "strip_markers_template",
+						Comments: types.Comments{Leading: comments("// Template with strip markers")},
+						Value: &types.HeredocExpr{
+							Marker:   "EOT",
+							Indented: true,
+							Content:  "This is a template with strip markers.\n%{if var.enable_feature~}\nThe feature is enabled.\n%{else~}\nThe feature is disabled.\n%{endif~}\n    \nAvailable items:\n%{for item in var.items~}\n- ${item}\n%{endfor~}\n    \n%{if var.show_timestamp~}\nTimestamp: ${timestamp()}\n%{endif~}\n",
+							Parts: []types.Expression{
+								str("This is a template with strip markers.\n"),
+								&types.TemplateIfDirective{
+									Condition: ref("var", "enable_feature"),
+									TrueExpr:  []types.Expression{str("\nThe feature is enabled.\n")},
+									FalseExpr: []types.Expression{str("\nThe feature is disabled.\n")},
+									Else:      true,
+									Strip:     types.TemplateStrip{Right: true},
+									ElseStrip: types.TemplateStrip{Right: true},
+									EndStrip:  types.TemplateStrip{Right: true},
+								},
+								str("\n    \nAvailable items:\n"),
+								&types.TemplateForDirective{
+									ValueVar: "item",
+									CollExpr: ref("var", "items"),
+									Content:  []types.Expression{str("\n- "), ref("item"), str("\n")},
+									Strip:    types.TemplateStrip{Right: true},
+									EndStrip: types.TemplateStrip{Right: true},
+								},
+								str("\n    \n"),
+								&types.TemplateIfDirective{
+									Condition: ref("var", "show_timestamp"),
+									TrueExpr: []types.Expression{
+										str("\nTimestamp: "),
+										&types.FunctionCallExpr{Name: "timestamp"},
+										str("\n"),
+									},
+									Strip:    types.TemplateStrip{Right: true},
+									EndStrip: types.TemplateStrip{Right: true},
+								},
+								str("\n"),
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "indented_template",
+						Comments: types.Comments{Leading: comments("// Template with indentation")},
+						Value: &types.HeredocExpr{
+							Marker:   "EOT",
+							Indented: true,
+							Content:  "server {\n  listen 80;\n  server_name ${var.domain_name};\n      \n  location / {\n    proxy_pass http://${var.backend_host}:${var.backend_port};\n    %{if var.enable_ssl~}\n    proxy_ssl_verify on;\n    proxy_ssl_trusted_certificate /etc/ssl/certs/ca-certificates.crt;\n    %{endif~}\n  }\n      \n  %{for path, config in var.extra_locations~}\n  location ${path} {\n    %{for key, value in config~}\n    ${key} ${value};\n    %{endfor~}\n  }\n  %{endfor~}\n}\n",
+						},
+					},
+					&types.Attribute{
+						Name:     "policy_template",
+						Comments: types.Comments{Leading: comments("// Heredoc with a custom marker")},
+						Value: &types.HeredocExpr{
+							Marker:  "POLICY",
+							Content: "{\n  \"Resource\": \"${var.bucket_arn}/*\",\n  \"Escaped\": \"$${aws:username}\"\n}\n",
+							Parts: []types.Expression{
+								str("{\n  \"Resource\": \""),
+								ref("var", "bucket_arn"),
+								str("/*\",\n  \"Escaped\": \"${aws:username}\"\n}\n"),
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "escaped_template",
+						Comments: types.Comments{Leading: comments("// Template with escaping")},
+						Value: &types.TemplateExpr{
+							Parts: []types.Expression{
+								str("Escaped interpolation: ${var.name}, Escaped directive: "),
+								&types.TemplateIfDirective{
+									Condition: &types.LiteralValue{Value: true, ValueType: "bool"},
+									TrueExpr:  []types.Expression{str("not processed")},
+								},
+							},
+						},
+					},
+					&types.Attribute{
+						Name:     "special_chars_template",
+						Comments: types.Comments{Leading: comments("// Template with special characters")},
+						Value:    str("Special chars: !@#$%^&*()_+-=[]{}|;:'\",.<>?/\\`~"),
+					},
+					&types.Attribute{
+						Name:     "unicode_template",
+						Comments: types.Comments{Leading: comments("// Template with unicode")},
+						Value:    str("Unicode: こんにちは世界 • Hello, World! • Привет, мир! • مرحبا بالعالم • 你好，世界！"),
+					},
+					&types.Attribute{
+						Name:     "newlines_template",
+						Comments: types.Comments{Leading: comments("// Template with newlines and tabs")},
+						Value:    str("Line 1\nLine 2\n\tIndented line\nLine 4"),
+					},
+				},
+			},
+			&types.Block{
+				Type:     "resource",
+				Labels:   []string{"aws_instance", "template_directives"},
+				Comments: types.Comments{Leading: comments("// Resource with template directives")},
+				Children: []types.Body{
+					&types.Attribute{Name: "ami", Value: str("ami-12345678")},
+					&types.Attribute{Name: "instance_type", Value: str("t2.micro")},
+					&types.Attribute{
+						Name:     "user_data",
+						Comments: types.Comments{Leading: comments("// User data with template directives")},
+						Value: &types.HeredocExpr{
+							Marker:   "EOF",
+							Indented: true,
+							Content:  "#!/bin/bash\n    \n# Set variables\nHOSTNAME=\"${var.hostname}\"\nENVIRONMENT=\"${var.environment}\"\n    \n# Update system\napt-get update\napt-get upgrade -y\n    \n# Install packages\n%{for package in var.packages~}\napt-get install -y ${package}\n%{endfor~}\n    \n# Configure services\n%{if var.enable_nginx~}\n# Nginx configuration\ncat > /etc/nginx/sites-available/default <<'NGINX'\nserver {\n  listen 80;\n  server_name ${var.domain_name};\n      \n  location / {\n    proxy_pass http://localhost:${var.app_port};\n  }\n}\nNGINX\n    \nsystemctl enable nginx\nsystemctl start nginx\n%{endif~}\n    \n# Set hostname\nhostnamectl set-hostname ${var.hostname}\n    \n# Create users\n%{for username, user_config in var.users~}\n# Create user ${username}\nuseradd -m -s /bin/bash ${username}\n%{if user_config.sudo~}\nusermod -aG sudo ${username}\n%{endif~}\n%{if user_config.ssh_key != \"\"~}\nmkdir -p /home/${username}/.ssh\necho \"${user_config.ssh_key}\" > /home/${username}/.ssh/authorized_keys\nchmod 600 /home/${username}/.ssh/authorized_keys\nchown -R ${username}:${username} /home/${username}/.ssh\n%{endif~}\n%{endfor~}\n    \n# Final message\necho \"Setup completed for ${var.hostname} in ${var.environment} environment\"\n",
+						},
+					},
+					&types.Attribute{
+						Name:     "tags",
+						Comments: types.Comments{Leading: comments("// Tags with template directives")},
+						Value: &types.ObjectExpr{
+							Items: []types.ObjectItem{
+								{
+									Key: ref("Name"),
+									Value: &types.TemplateExpr{
+										Parts: []types.Expression{ref("var", "name_prefix"), str("-instance")},
+									},
+								},
+								{Key: ref("Environment"), Value: ref("var", "environment")},
+								{Key: ref("CreatedAt"), Value: &types.FunctionCallExpr{Name: "timestamp"}},
+								{Key: ref("CreatedBy"), Value: str("Terraform")},
+								{
+									Key: ref("Owner"),
+									Value: &types.TemplateExpr{
+										Parts: []types.Expression{
+											&types.TemplateIfDirective{
+												Condition: ref("var", "include_owner"),
+												TrueExpr:  []types.Expression{ref("var", "owner")},
+												FalseExpr: []types.Expression{str("unassigned")},
+												Else:      true,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Comments: types.Comments{Leading: comments("// This file contains complex template directives and interpolation to test the parser")},
+	}
+}
+
+// createCommentsExpected creates the expected structure for comments_test.tf
+func createCommentsExpected() types.Body {
+	return &types.Root{
+		Children: []types.Body{
+			&types.Block{
+				Type:   "resource",
+				Labels: []string{"aws_instance", "web"},
+				Comments: types.Comments{
+					Leading:  comments("# Leading comment of a block"),
+					Trailing: comments("# After the opening brace", "# After the closing brace"),
+					Dangling: comments("# After the last attribute"),
+				},
+				Children: []types.Body{
+					&types.Attribute{
+						Name: "ami",
+						Value: &types.LiteralValue{
+							Value:     "ami-12345678",
+							ValueType: "string",
+						},
+						Comments: types.Comments{
+							Leading:  comments("// Leading comment of an attribute"),
+							Trailing: comments("# Trailing comment of an attribute"),
+						},
+					},
+					&types.Attribute{
+						Name: "security_groups",
+						Value: &types.ArrayExpr{
+							Items: []types.Expression{
+								&types.LiteralValue{Value: "sg-1", ValueType: "string"},
+								&types.LiteralValue{Value: "sg-2", ValueType: "string"},
+							},
+							ItemComments: []types.Comments{
+								{Trailing: comments("# Trailing comment of an item")},
+								{Leading: comments("# Leading comment of an item")},
+							},
+							Comments: types.Comments{
+								Trailing: comments("# After the opening bracket"),
+								Dangling: comments("# After the last item"),
+							},
+						},
+					},
+					&types.Attribute{
+						Name: "tags",
+						Value: &types.ObjectExpr{
+							Items: []types.ObjectItem{
+								{
+									Key: &types.ReferenceExpr{
+										Parts: []string{"Name"},
+									},
+									Value: &types.LiteralValue{
+										Value:     "web",
+										ValueType: "string",
+									},
+									Comments: types.Comments{
+										Leading:  comments("# Leading comment of an object item"),
+										Trailing: comments("// Trailing comment of an object item"),
+									},
+								},
+							},
+							Comments: types.Comments{
+								Dangling: comments("# After the last object item"),
+							},
+						},
+					},
+				},
+			},
+		},
+		Comments: types.Comments{
+			Leading:  comments("/*\n * Comments in every position the parser attaches them to\n */"),
+			Dangling: comments("# At the end of the file", "/* Block comment at the end of the file */"),
+		},
+	}
+}
+
+// createDirectivesExpected creates the expected structure for directives_test.tf
+func createDirectivesExpected() types.Body {
+	number := func(value int64) *types.LiteralValue {
+		return &types.LiteralValue{Value: value, ValueType: "number"}
+	}
+
+	return &types.Root{
+		Children: []types.Body{
+			&types.FormatDirective{
+				DirectiveType: "terralint-ignore-file",
+				Parameters:    []string{"order"},
+			},
+			&types.FormatDirective{
+				DirectiveType: "terralint-ignore",
+				Parameters:    []string{"format", "order"},
+				Comments: types.Comments{
+					Leading: comments("# Reviewed with the network team"),
+				},
+			},
+			&types.Block{
+				Type:   "resource",
+				Labels: []string{"aws_instance", "web"},
+				Children: []types.Body{
+					&types.Attribute{
+						Name: "ami",
+						Value: &types.LiteralValue{
+							Value:     "ami-12345678",
+							ValueType: "string",
+						},
+						Comments: types.Comments{
+							Trailing: comments("# terralint-ignore format"),
+						},
+					},
+					&types.FormatDirective{
+						DirectiveType: "terralint:format",
+						Parameters:    []string{"off"},
+					},
+					&types.Attribute{
+						Name: "matrix",
+						Value: &types.ArrayExpr{
+							Items: []types.Expression{number(1), number(0), number(0), number(1)},
+						},
+					},
+					&types.FormatDirective{
+						DirectiveType: "terralint:format",
+						Parameters:    []string{"on"},
+					},
+					&types.Attribute{
+						Name: "tags",
+						Value: &types.ObjectExpr{
+							Items: []types.ObjectItem{
+								{
+									Key: &types.ReferenceExpr{
+										Parts: []string{"Name"},
+									},
+									Value: &types.LiteralValue{
+										Value:     "web",
+										ValueType: "string",
+									},
+									Comments: types.Comments{
+										Leading: comments("# terralint-ignore format"),
+									},
+								},
+							},
+						},
+						Comments: types.Comments{
+							Leading: comments("# terralint-ignored is not a directive"),
+						},
+					},
+				},
+			},
+		},
 	}
 }
 
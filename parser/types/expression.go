@@ -14,6 +14,7 @@ type Expression interface {
 type LiteralValue struct {
 	Value     interface{} // The actual value
 	ValueType string      // Type of the value (string, number, bool)
+	Token     string      // The number as written in the source, which Value can't always hold exactly
 	ExprRange hcl.Range
 }
 
@@ -28,15 +29,15 @@ func (l *LiteralValue) Range() hcl.Range {
 // ObjectExpr represents object/map expressions
 type ObjectExpr struct {
 	Items     []ObjectItem
+	Comments  Comments // Trailing follows the opening brace, Dangling comes after the last item
 	ExprRange hcl.Range
 }
 
 type ObjectItem struct {
-	Key           Expression
-	Value         Expression
-	InlineComment string
-	BlockComment  string // Added to capture block comments
-	ExprRange     hcl.Range
+	Key       Expression
+	Value     Expression
+	Comments  Comments
+	ExprRange hcl.Range
 }
 
 func (o *ObjectItem) ExpressionType() string {
@@ -57,8 +58,10 @@ func (o *ObjectExpr) Range() hcl.Range {
 
 // ArrayExpr represents array/list expressions
 type ArrayExpr struct {
-	Items     []Expression
-	ExprRange hcl.Range
+	Items        []Expression
+	ItemComments []Comments // The comments of each item, nil when no item has any
+	Comments     Comments   // Trailing follows the opening bracket, Dangling comes after the last item
+	ExprRange    hcl.Range
 }
 
 func (a *ArrayExpr) ExpressionType() string {
@@ -85,9 +88,12 @@ func (r *ReferenceExpr) Range() hcl.Range {
 
 // FunctionCallExpr represents function calls
 type FunctionCallExpr struct {
-	Name      string
-	Args      []Expression
-	ExprRange hcl.Range
+	Name        string
+	Args        []Expression
+	ArgComments []Comments // The comments of each argument, nil when no argument has any
+	ExpandFinal bool       // Whether the final argument is expanded with "..."
+	Comments    Comments   // Trailing follows the opening parenthesis, Dangling comes after the last argument
+	ExprRange   hcl.Range
 }
 
 func (f *FunctionCallExpr) ExpressionType() string {
@@ -130,10 +136,12 @@ func (c *ConditionalExpr) Range() hcl.Range {
 
 // BinaryExpr represents binary operations
 type BinaryExpr struct {
-	Left      Expression
-	Operator  string // Should support: ==, !=, <, >, <=, >=, &&, ||, +, -, *, /, %, etc.
-	Right     Expression
-	ExprRange hcl.Range
+	Left          Expression
+	Operator      string // Should support: ==, !=, <, >, <=, >=, &&, ||, +, -, *, /, %, etc.
+	Right         Expression
+	LeftComments  Comments // Trailing holds the comments after the left operand, on the line it ends on
+	RightComments Comments // Leading holds the other comments between the operands
+	ExprRange     hcl.Range
 }
 
 func (b *BinaryExpr) ExpressionType() string {
@@ -159,6 +167,7 @@ type ForMapExpr struct {
 
 	// Filtering and grouping
 	Condition Expression // Optional "if" condition (e.g., "x != null" in "for x in xs : x if x != null")
+	Grouped   bool       // Whether values are grouped by key with "..." (e.g., "k => v..." in "for k, v in map : k => v...")
 
 	// Source location
 	ExprRange hcl.Range
@@ -202,7 +211,8 @@ func (f *ForArrayExpr) Range() hcl.Range {
 // SplatExpr represents splat expressions: aws_instance.server[*].id
 type SplatExpr struct {
 	Source    Expression // Expression being splattered
-	Each      Expression // Expression to evaluate for each element
+	Each      Expression // Expression to evaluate for each element, relative to an AnonSymbolExpr
+	AttrOnly  bool       // Whether it's the legacy attribute-only form: aws_instance.server.*.id
 	ExprRange hcl.Range
 }
 
@@ -214,11 +224,26 @@ func (s *SplatExpr) Range() hcl.Range {
 	return s.ExprRange
 }
 
+// AnonSymbolExpr stands for the element a splat is applied to, it's the
+// source of the Each of a splat: the element in aws_instance.server[*].id
+type AnonSymbolExpr struct {
+	ExprRange hcl.Range
+}
+
+func (a *AnonSymbolExpr) ExpressionType() string {
+	return "anon_symbol"
+}
+
+func (a *AnonSymbolExpr) Range() hcl.Range {
+	return a.ExprRange
+}
+
 // HeredocExpr represents heredoc strings
 type HeredocExpr struct {
-	Marker    string // The heredoc marker (e.g., "EOT")
-	Content   string // The content of the heredoc
-	Indented  bool   // Whether it's an indented heredoc (<<-)
+	Marker    string       // The heredoc marker (e.g., "EOT")
+	Content   string       // The content of the heredoc as written, without the indentation removed by <<-
+	Indented  bool         // Whether it's an indented heredoc (<<-)
+	Parts     []Expression // The literals, interpolations and directives of the content
 	ExprRange hcl.Range
 }
 
@@ -262,6 +287,7 @@ func (t *TupleExpr) Range() hcl.Range {
 // ParenExpr represents parenthesized expressions
 type ParenExpr struct {
 	Expression Expression
+	Comments   Comments // The comments of the expression inside, Dangling come after it before the closing parenthesis
 	ExprRange  hcl.Range
 }
 
@@ -309,12 +335,20 @@ func (r *RelativeTraversalExpr) Range() hcl.Range {
 	return r.ExprRange
 }
 
+// TemplateStrip records the whitespace strip markers of a template directive tag: %{~ if x ~}
+type TemplateStrip struct {
+	Left  bool // Whether the tag opens with %{~
+	Right bool // Whether the tag closes with ~}
+}
+
 // TemplateForDirective represents for loops within template strings
 type TemplateForDirective struct {
-	KeyVar    string       // Optional key variable for maps
-	ValueVar  string       // Value variable
-	CollExpr  Expression   // Collection to iterate over
-	Content   []Expression // Content to repeat for each iteration
+	KeyVar    string        // Optional key variable for maps
+	ValueVar  string        // Value variable
+	CollExpr  Expression    // Collection to iterate over
+	Content   []Expression  // Content to repeat for each iteration
+	Strip     TemplateStrip // Strip markers of the for tag
+	EndStrip  TemplateStrip // Strip markers of the endfor tag
 	ExprRange hcl.Range
 }
 
@@ -331,6 +365,10 @@ type TemplateIfDirective struct {
 	Condition Expression
 	TrueExpr  []Expression
 	FalseExpr []Expression
+	Else      bool          // Whether there's an else tag, the else branch can still be empty
+	Strip     TemplateStrip // Strip markers of the if tag
+	ElseStrip TemplateStrip // Strip markers of the else tag
+	EndStrip  TemplateStrip // Strip markers of the endif tag
 	ExprRange hcl.Range
 }
 
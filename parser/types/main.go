@@ -10,17 +10,66 @@ type Body interface {
 // Root represents the top-level HCL document
 type Root struct {
 	Children []Body
+	// Leading holds the header comments a blank line sets off from the first
+	// item, Dangling the comments after the last item or in a file without items
+	Comments Comments
 }
 
 func (r *Root) BodyType() string {
 	return "root"
 }
 
-// FormatDirective represents formatter and linter directives like # tflint-ignore
+// Comment is a single comment as written in the source, including its #, //
+// or /* */ markers
+type Comment struct {
+	Text  string
+	Range hcl.Range
+}
+
+// Comments holds the comments attached to a node. Leading comments come before
+// the node, trailing comments follow code on the line the node starts or ends
+// on and dangling comments are inside the node without belonging to any of
+// its children.
+type Comments struct {
+	Leading  []Comment
+	Trailing []Comment
+	Dangling []Comment
+}
+
+// Empty reports whether no comments are attached
+func (c Comments) Empty() bool {
+	return len(c.Leading) == 0 && len(c.Trailing) == 0 && len(c.Dangling) == 0
+}
+
+// Directive types recognized in comments on a line of their own
+const (
+	// DirectiveIgnore silences the listed rules, or all of them, for the item
+	// that follows it
+	DirectiveIgnore = "terralint-ignore"
+	// DirectiveIgnoreFile silences the listed rules, or all of them, for the file
+	DirectiveIgnoreFile = "terralint-ignore-file"
+	// DirectiveFormat turns formatting "off" until the next "on" directive in
+	// the same body, or until the end of the body
+	DirectiveFormat = "terralint:format"
+)
+
+// FormatDirective represents formatter and linter directives like # terralint-ignore
 type FormatDirective struct {
-	DirectiveType string   // The directive type (e.g., "tflint-ignore")
+	DirectiveType string   // The directive type (e.g., "terralint-ignore")
 	Parameters    []string // Any parameters for the directive
+	Text          string   // The comment the directive was read from, printed as is when set
 	Range         hcl.Range
+	Comments      Comments // Leading holds the comments on the lines before the directive
+}
+
+// FormattingOff reports whether the directive turns formatting off
+func (f *FormatDirective) FormattingOff() bool {
+	return f.DirectiveType == DirectiveFormat && len(f.Parameters) == 1 && f.Parameters[0] == "off"
+}
+
+// FormattingOn reports whether the directive turns formatting back on
+func (f *FormatDirective) FormattingOn() bool {
+	return f.DirectiveType == DirectiveFormat && len(f.Parameters) == 1 && f.Parameters[0] == "on"
 }
 
 func (f *FormatDirective) BodyType() string {
@@ -29,12 +78,11 @@ func (f *FormatDirective) BodyType() string {
 
 // Block represents a Terraform block (resource, data, module, etc.)
 type Block struct {
-	Type          string    // The type of block (resource, data, variable, etc.)
-	Labels        []string  // Labels/identifiers for the block (e.g., "aws_instance" "example")
-	Range         hcl.Range // Source code position information
-	InlineComment string    // Inline comment if present
-	BlockComment  string    // Block comment if present
-	Children      []Body    // Nested blocks and attributes
+	Type     string    // The type of block (resource, data, variable, etc.)
+	Labels   []string  // Labels/identifiers for the block (e.g., "aws_instance" "example")
+	Range    hcl.Range // Source code position information
+	Comments Comments  // Dangling holds the comments after the last child
+	Children []Body    // Nested blocks and attributes
 }
 
 func (b *Block) BodyType() string {
@@ -43,11 +91,10 @@ func (b *Block) BodyType() string {
 
 // Attribute represents a key-value pair in HCL
 type Attribute struct {
-	Name          string     // The name of the attribute
-	Value         Expression // The value of the attribute
-	Range         hcl.Range
-	InlineComment string
-	BlockComment  string
+	Name     string     // The name of the attribute
+	Value    Expression // The value of the attribute
+	Range    hcl.Range
+	Comments Comments
 }
 
 func (a *Attribute) BodyType() string {
@@ -0,0 +1,274 @@
+package types
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Declaration kinds, named after the blocks declaring them
+const (
+	KindVariable  = "variable"
+	KindLocal     = "local" // The attributes of locals blocks
+	KindOutput    = "output"
+	KindResource  = "resource"
+	KindData      = "data"
+	KindModule    = "module"
+	KindProvider  = "provider"
+	KindTerraform = "terraform"
+)
+
+// ModuleFile is one of the files of a module
+type ModuleFile struct {
+	Path     string
+	Content  []byte
+	Root     *Root
+	Override bool // Whether it's an override file, whose blocks amend the declarations of the other files
+}
+
+// IsOverrideFile reports whether Terraform treats the file at path as an
+// override file, which is one named override.tf or ending in _override.tf
+func IsOverrideFile(path string) bool {
+	name := strings.TrimSuffix(filepath.Base(path), ".tf")
+	return name == "override" || strings.HasSuffix(name, "_override")
+}
+
+// Declaration is something a module declares at its top level, along with the
+// file and the range it was declared at
+type Declaration struct {
+	Kind      string     // One of the declaration kinds
+	Type      string     // The type of resources and data sources, and the name of providers
+	Name      string     // The name, which is the alias of providers and empty for terraform blocks
+	File      string     // The path of the declaring file
+	Range     hcl.Range  // The range of the declaring block, or attribute for locals
+	Block     *Block     // The declaring block, or the block holding the declaring attribute
+	Attribute *Attribute // The attribute declaring a local, or the entry of required_providers listing a configuration alias, nil otherwise
+}
+
+// Address returns the address the declaration is known by in the module, like
+// var.region, aws_instance.app or data.aws_ami.ubuntu. It's the address
+// references use for the kinds that can be referenced.
+func (d *Declaration) Address() string {
+	switch d.Kind {
+	case KindVariable:
+		return "var." + d.Name
+	case KindResource:
+		return d.Type + "." + d.Name
+	case KindData:
+		return "data." + d.Type + "." + d.Name
+	case KindProvider:
+		if d.Name == "" {
+			return "provider." + d.Type
+		}
+		return "provider." + d.Type + "." + d.Name
+	case KindTerraform:
+		return KindTerraform
+	}
+	return d.Kind + "." + d.Name
+}
+
+// Module is the merged view of the .tf files of a directory, which Terraform
+// evaluates together as one module. The declarations of every kind are in the
+// order of the files and of their position in them.
+type Module struct {
+	Dir         string
+	Files       []*ModuleFile
+	Variables   []*Declaration
+	Locals      []*Declaration
+	Outputs     []*Declaration
+	Resources   []*Declaration
+	DataSources []*Declaration
+	ModuleCalls []*Declaration
+	Providers   []*Declaration
+	Terraform   []*Declaration // The terraform blocks, which configure the module itself
+
+	declarations []*Declaration
+	addresses    map[string][]*Declaration
+	resolveOnce  sync.Once
+	bindings     []*Binding
+}
+
+// NewModule returns a module of the directory without any files
+func NewModule(dir string) *Module {
+	return &Module{Dir: dir, addresses: make(map[string][]*Declaration)}
+}
+
+// AddFile adds a parsed file to the module along with the declarations at its
+// top level. The blocks of override files don't declare anything, they only
+// change what the other files declare.
+func (m *Module) AddFile(file *ModuleFile) {
+	m.Files = append(m.Files, file)
+	if file.Override {
+		return
+	}
+
+	for _, child := range file.Root.Children {
+		block, ok := child.(*Block)
+		if !ok {
+			continue
+		}
+		for _, declaration := range declarations(block, file.Path) {
+			m.add(declaration)
+		}
+	}
+}
+
+func (m *Module) add(declaration *Declaration) {
+	switch declaration.Kind {
+	case KindVariable:
+		m.Variables = append(m.Variables, declaration)
+	case KindLocal:
+		m.Locals = append(m.Locals, declaration)
+	case KindOutput:
+		m.Outputs = append(m.Outputs, declaration)
+	case KindResource:
+		m.Resources = append(m.Resources, declaration)
+	case KindData:
+		m.DataSources = append(m.DataSources, declaration)
+	case KindModule:
+		m.ModuleCalls = append(m.ModuleCalls, declaration)
+	case KindProvider:
+		m.Providers = append(m.Providers, declaration)
+	case KindTerraform:
+		m.Terraform = append(m.Terraform, declaration)
+	}
+
+	m.declarations = append(m.declarations, declaration)
+	address := declaration.Address()
+	m.addresses[address] = append(m.addresses[address], declaration)
+}
+
+// Declarations returns every declaration of the module in the order of the
+// files and of their position in them
+func (m *Module) Declarations() []*Declaration {
+	return m.declarations
+}
+
+// Lookup returns the declarations with an address, of which there is more than
+// one when the address is declared again
+func (m *Module) Lookup(address string) []*Declaration {
+	return m.addresses[address]
+}
+
+// File returns the file of the module at path, or nil when there is none
+func (m *Module) File(path string) *ModuleFile {
+	for _, file := range m.Files {
+		if file.Path == path {
+			return file
+		}
+	}
+	return nil
+}
+
+// declarations returns what a top level block declares. Blocks missing their
+// labels, and the blocks of other types, declare nothing.
+func declarations(block *Block, file string) []*Declaration {
+	declaration := &Declaration{Kind: block.Type, File: file, Range: block.Range, Block: block}
+
+	switch block.Type {
+	case KindVariable, KindOutput, KindModule:
+		if len(block.Labels) != 1 {
+			return nil
+		}
+		declaration.Name = block.Labels[0]
+	case KindResource, KindData:
+		if len(block.Labels) != 2 {
+			return nil
+		}
+		declaration.Type, declaration.Name = block.Labels[0], block.Labels[1]
+	case KindProvider:
+		if len(block.Labels) != 1 {
+			return nil
+		}
+		declaration.Type = block.Labels[0]
+		declaration.Name = providerAlias(block)
+	case KindTerraform:
+		if len(block.Labels) != 0 {
+			return nil
+		}
+		return append([]*Declaration{declaration}, configurationAliases(block, file)...)
+	case "locals":
+		var locals []*Declaration
+		for _, child := range block.Children {
+			if attribute, ok := child.(*Attribute); ok {
+				locals = append(locals, &Declaration{
+					Kind:      KindLocal,
+					Name:      attribute.Name,
+					File:      file,
+					Range:     attribute.Range,
+					Block:     block,
+					Attribute: attribute,
+				})
+			}
+		}
+		return locals
+	default:
+		return nil
+	}
+	return []*Declaration{declaration}
+}
+
+// providerAlias returns the alias of a provider block, or an empty string when
+// it's the default configuration of the provider
+func providerAlias(block *Block) string {
+	for _, child := range block.Children {
+		if attribute, ok := child.(*Attribute); ok && attribute.Name == "alias" {
+			if literal, ok := attribute.Value.(*LiteralValue); ok {
+				if alias, ok := literal.Value.(string); ok {
+					return alias
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// configurationAliases returns the provider configurations the
+// configuration_aliases of the required_providers of a terraform block declare,
+// which the calling module passes in
+func configurationAliases(terraform *Block, file string) []*Declaration {
+	var aliases []*Declaration
+	for _, child := range terraform.Children {
+		block, ok := child.(*Block)
+		if !ok || block.Type != "required_providers" {
+			continue
+		}
+		for _, nested := range block.Children {
+			attribute, ok := nested.(*Attribute)
+			if !ok {
+				continue
+			}
+			requirement, ok := attribute.Value.(*ObjectExpr)
+			if !ok {
+				continue
+			}
+			for _, item := range requirement.Items {
+				if key, ok := item.Key.(*ReferenceExpr); !ok || len(key.Parts) != 1 || key.Parts[0] != "configuration_aliases" {
+					continue
+				}
+				list, ok := item.Value.(*ArrayExpr)
+				if !ok {
+					continue
+				}
+				for _, entry := range list.Items {
+					reference, ok := entry.(*ReferenceExpr)
+					if !ok || len(reference.Parts) != 2 || reference.Parts[0] != attribute.Name {
+						continue
+					}
+					aliases = append(aliases, &Declaration{
+						Kind:      KindProvider,
+						Type:      reference.Parts[0],
+						Name:      reference.Parts[1],
+						File:      file,
+						Range:     reference.Range(),
+						Block:     block,
+						Attribute: attribute,
+					})
+				}
+			}
+		}
+	}
+	return aliases
+}
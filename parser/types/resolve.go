@@ -0,0 +1,348 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Binding kinds, telling what a reference resolves to
+const (
+	BindingDeclaration = "declaration" // A declaration of the module
+	BindingIterator    = "iterator"    // The variable of a for expression, template for directive or dynamic block
+	BindingBuiltin     = "builtin"     // each, count, self, path, terraform or the implicit configuration of a provider
+	BindingUnresolved  = "unresolved"  // Nothing, the reference is undefined
+)
+
+// Binding is what a reference of a module resolves to
+type Binding struct {
+	Reference   *ReferenceExpr
+	File        string       // The path of the file holding the reference
+	Kind        string       // One of the binding kinds
+	Declaration *Declaration // The referenced declaration of BindingDeclaration
+	Scope       Node         // The expression or dynamic block declaring the variable of BindingIterator
+	Problem     string       // Why a BindingUnresolved reference doesn't resolve
+}
+
+// Resolve binds every reference of the module to what it refers to, in the
+// order of the files and of the position of the references in them. The
+// bindings are computed once and shared by every caller, which must not
+// modify them.
+func (m *Module) Resolve() []*Binding {
+	m.resolveOnce.Do(func() {
+		for _, file := range m.Files {
+			r := resolver{module: m, file: file.Path, bindings: &m.bindings}
+			Walk(r, file.Root)
+		}
+	})
+	return m.bindings
+}
+
+// ReferenceCounts returns how many references of the module resolve to each of
+// its declarations. The references a declaration makes to itself, like the
+// validations of a variable, don't count.
+func (m *Module) ReferenceCounts() map[*Declaration]int {
+	counts := make(map[*Declaration]int)
+	for _, binding := range m.Resolve() {
+		declaration := binding.Declaration
+		if declaration == nil {
+			continue
+		}
+		rng := binding.Reference.Range()
+		if binding.File == declaration.File && declaration.Range.ContainsOffset(rng.Start.Byte) {
+			continue
+		}
+		counts[declaration]++
+	}
+	return counts
+}
+
+// resolver is the visitor binding the references of a file. It's copied for
+// every block and expression declaring variables, so that they are only in
+// scope below it.
+type resolver struct {
+	module    *Module
+	file      string
+	blocks    []*Block        // The blocks enclosing the node, the top level one first
+	iterators map[string]Node // The variables in scope and what declares them
+	providers bool            // Whether the references name provider configurations
+	bindings  *[]*Binding
+}
+
+func (r resolver) Visit(node Node) Visitor {
+	switch n := node.(type) {
+	case *Block:
+		// The addresses of moved and removed blocks name what no longer exists
+		if len(r.blocks) == 0 && (n.Type == KindTerraform || n.Type == "moved" || n.Type == "removed") {
+			return nil
+		}
+		if n.Type == "dynamic" && len(n.Labels) == 1 {
+			r.dynamic(n)
+			return nil
+		}
+		r.blocks = append(r.blocks[:len(r.blocks):len(r.blocks)], n)
+	case *Attribute:
+		if r.declarative(n) {
+			return nil
+		}
+		r.providers = r.namesProviders(n)
+	case *ObjectItem:
+		// A bare name as a key is the string it spells
+		if key, ok := n.Key.(*ReferenceExpr); ok && len(key.Parts) == 1 {
+			r.walk(n.Value)
+			return nil
+		}
+	case *ForArrayExpr:
+		r.walk(n.Collection)
+		inner := r.declare(n, n.KeyVar, n.ValueVar)
+		inner.walk(n.ThenValueExpr)
+		inner.walk(n.Condition)
+		return nil
+	case *ForMapExpr:
+		r.walk(n.Collection)
+		inner := r.declare(n, n.KeyVar, n.ValueVar)
+		inner.walk(n.ThenKeyExpr)
+		inner.walk(n.ThenValueExpr)
+		inner.walk(n.Condition)
+		return nil
+	case *TemplateForDirective:
+		r.walk(n.CollExpr)
+		inner := r.declare(n, n.KeyVar, n.ValueVar)
+		for _, part := range n.Content {
+			inner.walk(part)
+		}
+		return nil
+	case *ReferenceExpr:
+		*r.bindings = append(*r.bindings, r.bind(n))
+	}
+	return r
+}
+
+func (r resolver) walk(node Expression) {
+	if node != nil {
+		Walk(r, node)
+	}
+}
+
+// declare returns the resolver of the scope of the variables a node declares
+func (r resolver) declare(scope Node, names ...string) resolver {
+	iterators := make(map[string]Node, len(r.iterators)+len(names))
+	for name, node := range r.iterators {
+		iterators[name] = node
+	}
+	for _, name := range names {
+		if name != "" {
+			iterators[name] = scope
+		}
+	}
+	r.iterators = iterators
+	return r
+}
+
+// dynamic walks a dynamic block, whose iterator is named after the block unless
+// its iterator attribute names it. The collection is outside of its scope.
+func (r resolver) dynamic(block *Block) {
+	r.blocks = append(r.blocks[:len(r.blocks):len(r.blocks)], block)
+	name := block.Labels[0]
+	for _, child := range block.Children {
+		if attribute, ok := child.(*Attribute); ok && attribute.Name == "iterator" {
+			if reference, ok := attribute.Value.(*ReferenceExpr); ok && len(reference.Parts) == 1 {
+				name = reference.Parts[0]
+			}
+		}
+	}
+
+	inner := r.declare(block, name)
+	for _, child := range block.Children {
+		attribute, ok := child.(*Attribute)
+		switch {
+		case ok && attribute.Name == "iterator":
+		case ok && attribute.Name == "for_each":
+			Walk(r, child)
+		default:
+			Walk(inner, child)
+		}
+	}
+}
+
+// declarative reports whether the value of an attribute isn't evaluated, but
+// spells out names like types or the attributes to ignore
+func (r resolver) declarative(attribute *Attribute) bool {
+	if len(r.blocks) == 0 {
+		return false
+	}
+	parent := r.blocks[len(r.blocks)-1]
+	return attribute.Name == "type" && parent.Type == KindVariable && len(r.blocks) == 1 ||
+		attribute.Name == "ignore_changes" && parent.Type == "lifecycle"
+}
+
+// namesProviders reports whether the value of an attribute refers to provider
+// configurations, which are named without a prefix
+func (r resolver) namesProviders(attribute *Attribute) bool {
+	if len(r.blocks) != 1 {
+		return false
+	}
+	switch r.blocks[0].Type {
+	case KindResource, KindData, "import":
+		return attribute.Name == "provider"
+	case KindModule:
+		return attribute.Name == "providers"
+	}
+	return false
+}
+
+// topLevel returns the top level block enclosing the node, or nil at the top level
+func (r resolver) topLevel() *Block {
+	if len(r.blocks) == 0 {
+		return nil
+	}
+	return r.blocks[0]
+}
+
+// inside reports whether the node is inside a block of one of the types
+func (r resolver) inside(blockTypes ...string) bool {
+	for _, block := range r.blocks {
+		for _, blockType := range blockTypes {
+			if block.Type == blockType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasAttribute reports whether the top level block sets an attribute
+func (r resolver) hasAttribute(name string) bool {
+	if block := r.topLevel(); block != nil {
+		for _, child := range block.Children {
+			if attribute, ok := child.(*Attribute); ok && attribute.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r resolver) bind(reference *ReferenceExpr) *Binding {
+	binding := &Binding{Reference: reference, File: r.file, Kind: BindingBuiltin}
+	parts := reference.Parts
+	unresolved := func(format string, a ...any) *Binding {
+		binding.Kind = BindingUnresolved
+		binding.Problem = fmt.Sprintf(format, a...)
+		return binding
+	}
+	attribute := func(allowed ...string) *Binding {
+		if len(parts) < 2 {
+			return unresolved("%s can't be used on its own", parts[0])
+		}
+		for _, name := range allowed {
+			if parts[1] == name {
+				return binding
+			}
+		}
+		return unresolved("%s has no attribute %q", parts[0], parts[1])
+	}
+
+	if scope, found := r.iterators[parts[0]]; found {
+		binding.Kind, binding.Scope = BindingIterator, scope
+		return binding
+	}
+	if r.providers {
+		return r.bindProvider(binding)
+	}
+
+	switch parts[0] {
+	case "var":
+		return r.bindDeclaration(binding, 2, "variable")
+	case "local":
+		return r.bindDeclaration(binding, 2, "local value")
+	case "module":
+		return r.bindDeclaration(binding, 2, "module")
+	case "data":
+		return r.bindDeclaration(binding, 3, "data source")
+	case "each":
+		if !r.hasAttribute("for_each") {
+			return unresolved("each can only be used in blocks with for_each")
+		}
+		return attribute("key", "value")
+	case "count":
+		if !r.hasAttribute("count") {
+			return unresolved("count can only be used in blocks with count")
+		}
+		return attribute("index")
+	case "self":
+		if !r.inside("provisioner", "connection", "postcondition") {
+			return unresolved("self can only be used in provisioner, connection and postcondition blocks")
+		}
+		return binding
+	case "path":
+		return attribute("module", "root", "cwd")
+	case "terraform":
+		return attribute("workspace", "applying")
+	}
+	if len(parts) < 2 {
+		return unresolved("unknown name %q", parts[0])
+	}
+	return r.bindDeclaration(binding, 2, "resource")
+}
+
+// bindDeclaration binds a reference to the declaration named by its first n
+// parts, described by kind in the problem of an unresolved reference
+func (r resolver) bindDeclaration(binding *Binding, n int, kind string) *Binding {
+	parts := binding.Reference.Parts
+	if len(parts) < n {
+		binding.Kind = BindingUnresolved
+		binding.Problem = fmt.Sprintf("incomplete reference to a %s", kind)
+		return binding
+	}
+
+	address := strings.Join(parts[:n], ".")
+	if declarations := r.module.Lookup(address); len(declarations) > 0 {
+		binding.Kind, binding.Declaration = BindingDeclaration, declarations[0]
+		return binding
+	}
+	if declaration := r.scopedData(parts[:n]); declaration != nil {
+		binding.Kind, binding.Declaration = BindingDeclaration, declaration
+		return binding
+	}
+
+	binding.Kind = BindingUnresolved
+	name := strings.Join(parts[1:n], ".")
+	if kind == "resource" {
+		name = address
+	}
+	binding.Problem = fmt.Sprintf("%s %q is not declared", kind, name)
+	return binding
+}
+
+// scopedData returns the data source a check block declares for itself, which
+// only its own assertions can refer to
+func (r resolver) scopedData(parts []string) *Declaration {
+	check := r.topLevel()
+	if check == nil || check.Type != "check" || len(parts) != 3 || parts[0] != KindData {
+		return nil
+	}
+	for _, child := range check.Children {
+		if block, ok := child.(*Block); ok && block.Type == KindData && len(block.Labels) == 2 &&
+			block.Labels[0] == parts[1] && block.Labels[1] == parts[2] {
+			return &Declaration{Kind: KindData, Type: parts[1], Name: parts[2], File: r.file, Range: block.Range, Block: block}
+		}
+	}
+	return nil
+}
+
+// bindProvider binds a reference to a provider configuration. The default
+// configuration of a provider exists without being declared.
+func (r resolver) bindProvider(binding *Binding) *Binding {
+	parts := binding.Reference.Parts
+	if declarations := r.module.Lookup("provider." + strings.Join(parts, ".")); len(declarations) > 0 {
+		binding.Kind, binding.Declaration = BindingDeclaration, declarations[0]
+		return binding
+	}
+	if len(parts) == 1 {
+		return binding
+	}
+
+	binding.Kind = BindingUnresolved
+	binding.Problem = fmt.Sprintf("provider configuration %q is not declared", strings.Join(parts, "."))
+	return binding
+}
@@ -0,0 +1,282 @@
+package types
+
+import "fmt"
+
+// Node is any element of the AST, either a Body or an Expression
+type Node interface{}
+
+// Visitor's Visit method is invoked for each node encountered by Walk. If the
+// result visitor w is not nil, Walk visits each of the children of node with
+// the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the non-nil children of node, followed by a call of w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	walk := func(child Node) {
+		if child != nil {
+			Walk(v, child)
+		}
+	}
+	walkExpr := func(expr Expression) {
+		if expr != nil {
+			Walk(v, expr)
+		}
+	}
+	walkExprs := func(exprs []Expression) {
+		for _, expr := range exprs {
+			walkExpr(expr)
+		}
+	}
+
+	switch n := node.(type) {
+	// Bodies
+	case *Root:
+		for _, child := range n.Children {
+			walk(child)
+		}
+	case *Block:
+		for _, child := range n.Children {
+			walk(child)
+		}
+	case *Attribute:
+		walkExpr(n.Value)
+	case *FormatDirective:
+		// Nothing to do
+
+	// Expressions
+	case *LiteralValue, *ReferenceExpr, *AnonSymbolExpr:
+		// Nothing to do
+	case *ObjectExpr:
+		for i := range n.Items {
+			Walk(v, &n.Items[i])
+		}
+	case *ObjectItem:
+		walkExpr(n.Key)
+		walkExpr(n.Value)
+	case *ArrayExpr:
+		walkExprs(n.Items)
+	case *TupleExpr:
+		walkExprs(n.Expressions)
+	case *FunctionCallExpr:
+		walkExprs(n.Args)
+	case *TemplateExpr:
+		walkExprs(n.Parts)
+	case *HeredocExpr:
+		walkExprs(n.Parts)
+	case *ConditionalExpr:
+		walkExpr(n.Condition)
+		walkExpr(n.TrueExpr)
+		walkExpr(n.FalseExpr)
+	case *BinaryExpr:
+		walkExpr(n.Left)
+		walkExpr(n.Right)
+	case *UnaryExpr:
+		walkExpr(n.Expr)
+	case *ParenExpr:
+		walkExpr(n.Expression)
+	case *ForArrayExpr:
+		walkExpr(n.Collection)
+		walkExpr(n.ThenValueExpr)
+		walkExpr(n.Condition)
+	case *ForMapExpr:
+		walkExpr(n.Collection)
+		walkExpr(n.ThenKeyExpr)
+		walkExpr(n.ThenValueExpr)
+		walkExpr(n.Condition)
+	case *SplatExpr:
+		walkExpr(n.Source)
+		walkExpr(n.Each)
+	case *IndexExpr:
+		walkExpr(n.Collection)
+		walkExpr(n.Key)
+	case *RelativeTraversalExpr:
+		walkExpr(n.Source)
+		for _, elem := range n.Traversal {
+			walkExpr(elem.Index)
+		}
+	case *TemplateForDirective:
+		walkExpr(n.CollExpr)
+		walkExprs(n.Content)
+	case *TemplateIfDirective:
+		walkExpr(n.Condition)
+		walkExprs(n.TrueExpr)
+		walkExprs(n.FalseExpr)
+
+	default:
+		panic(fmt.Sprintf("types.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a call
+// of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite traverses an AST in depth-first order and calls f for every node
+// after its children have been rewritten. The node returned by f takes the
+// place of the original one. Returning nil removes the node from the list
+// that holds it, or clears the field that holds it. Rewrite returns the node
+// that replaces the root.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch n := node.(type) {
+	// Bodies
+	case *Root:
+		n.Children = rewriteBodies(n.Children, f)
+	case *Block:
+		n.Children = rewriteBodies(n.Children, f)
+	case *Attribute:
+		n.Value = rewriteExpr(n.Value, f)
+	case *FormatDirective:
+		// Nothing to do
+
+	// Expressions
+	case *LiteralValue, *ReferenceExpr, *AnonSymbolExpr:
+		// Nothing to do
+	case *ObjectExpr:
+		items := n.Items[:0]
+		for i := range n.Items {
+			switch item := Rewrite(&n.Items[i], f).(type) {
+			case nil:
+			case *ObjectItem:
+				items = append(items, *item)
+			default:
+				panic(fmt.Sprintf("types.Rewrite: %T can't replace an object item", item))
+			}
+		}
+		n.Items = items
+	case *ObjectItem:
+		n.Key = rewriteExpr(n.Key, f)
+		n.Value = rewriteExpr(n.Value, f)
+	case *ArrayExpr:
+		n.Items, n.ItemComments = rewriteItems(n.Items, n.ItemComments, f)
+	case *TupleExpr:
+		n.Expressions = rewriteExprs(n.Expressions, f)
+	case *FunctionCallExpr:
+		n.Args, n.ArgComments = rewriteItems(n.Args, n.ArgComments, f)
+	case *TemplateExpr:
+		n.Parts = rewriteExprs(n.Parts, f)
+	case *HeredocExpr:
+		n.Parts = rewriteExprs(n.Parts, f)
+	case *ConditionalExpr:
+		n.Condition = rewriteExpr(n.Condition, f)
+		n.TrueExpr = rewriteExpr(n.TrueExpr, f)
+		n.FalseExpr = rewriteExpr(n.FalseExpr, f)
+	case *BinaryExpr:
+		n.Left = rewriteExpr(n.Left, f)
+		n.Right = rewriteExpr(n.Right, f)
+	case *UnaryExpr:
+		n.Expr = rewriteExpr(n.Expr, f)
+	case *ParenExpr:
+		n.Expression = rewriteExpr(n.Expression, f)
+	case *ForArrayExpr:
+		n.Collection = rewriteExpr(n.Collection, f)
+		n.ThenValueExpr = rewriteExpr(n.ThenValueExpr, f)
+		n.Condition = rewriteExpr(n.Condition, f)
+	case *ForMapExpr:
+		n.Collection = rewriteExpr(n.Collection, f)
+		n.ThenKeyExpr = rewriteExpr(n.ThenKeyExpr, f)
+		n.ThenValueExpr = rewriteExpr(n.ThenValueExpr, f)
+		n.Condition = rewriteExpr(n.Condition, f)
+	case *SplatExpr:
+		n.Source = rewriteExpr(n.Source, f)
+		n.Each = rewriteExpr(n.Each, f)
+	case *IndexExpr:
+		n.Collection = rewriteExpr(n.Collection, f)
+		n.Key = rewriteExpr(n.Key, f)
+	case *RelativeTraversalExpr:
+		n.Source = rewriteExpr(n.Source, f)
+		for i := range n.Traversal {
+			n.Traversal[i].Index = rewriteExpr(n.Traversal[i].Index, f)
+		}
+	case *TemplateForDirective:
+		n.CollExpr = rewriteExpr(n.CollExpr, f)
+		n.Content = rewriteExprs(n.Content, f)
+	case *TemplateIfDirective:
+		n.Condition = rewriteExpr(n.Condition, f)
+		n.TrueExpr = rewriteExprs(n.TrueExpr, f)
+		n.FalseExpr = rewriteExprs(n.FalseExpr, f)
+
+	default:
+		panic(fmt.Sprintf("types.Rewrite: unexpected node type %T", n))
+	}
+
+	return f(node)
+}
+
+func rewriteBodies(children []Body, f func(Node) Node) []Body {
+	result := children[:0]
+	for _, child := range children {
+		switch replacement := Rewrite(child, f).(type) {
+		case nil:
+		case Body:
+			result = append(result, replacement)
+		default:
+			panic(fmt.Sprintf("types.Rewrite: %T can't replace a body", replacement))
+		}
+	}
+	return result
+}
+
+func rewriteExpr(expr Expression, f func(Node) Node) Expression {
+	if expr == nil {
+		return nil
+	}
+	switch replacement := Rewrite(expr, f).(type) {
+	case nil:
+		return nil
+	case Expression:
+		return replacement
+	default:
+		panic(fmt.Sprintf("types.Rewrite: %T can't replace an expression", replacement))
+	}
+}
+
+func rewriteExprs(exprs []Expression, f func(Node) Node) []Expression {
+	result := exprs[:0]
+	for _, expr := range exprs {
+		if expr = rewriteExpr(expr, f); expr != nil {
+			result = append(result, expr)
+		}
+	}
+	return result
+}
+
+// rewriteItems rewrites the items of a list, keeping the comments of the items
+// that survive next to them
+func rewriteItems(items []Expression, itemComments []Comments, f func(Node) Node) ([]Expression, []Comments) {
+	result := items[:0]
+	var comments []Comments
+	for i, item := range items {
+		if item = rewriteExpr(item, f); item == nil {
+			continue
+		}
+		result = append(result, item)
+		if itemComments != nil {
+			comments = append(comments, itemComments[i])
+		}
+	}
+	return result, comments
+}
@@ -0,0 +1,1115 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/vahid-haghighat/terralint/parser/types"
+)
+
+// indentUnit is the indentation used for every nesting level
+const indentUnit = "  "
+
+var quotedEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"${", "$${",
+	"%{", "%%{",
+)
+
+var heredocEscaper = strings.NewReplacer(
+	"${", "$${",
+	"%{", "%%{",
+)
+
+// heredocOpener matches a line that ends by opening a heredoc, which nothing
+// can follow
+var heredocOpener = regexp.MustCompile(`<<-?[A-Za-z_][A-Za-z0-9_-]*$`)
+
+var labelEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+// Config controls how an AST is printed
+type Config struct {
+	// BlankLines decides how many blank lines separate two sibling items of a
+	// body. parent is nil for the top level body. A negative result keeps the
+	// spacing found in the source.
+	BlankLines func(parent *types.Block, previous, current types.Body) int
+	// Source is the content the AST was parsed from. The regions formatting is
+	// turned off for are copied from it, without it they are formatted too.
+	Source []byte
+}
+
+// Print renders the AST rooted at root back to canonical HCL
+func Print(root *types.Root) []byte {
+	return (&Config{}).Print(root)
+}
+
+// Fprint writes the canonical HCL form of root to w
+func Fprint(w io.Writer, root *types.Root) error {
+	return (&Config{}).Fprint(w, root)
+}
+
+// Print renders the AST rooted at root back to canonical HCL using the config
+func (c *Config) Print(root *types.Root) []byte {
+	p := &printer{config: c}
+	if len(root.Comments.Leading) > 0 {
+		// A blank line keeps the header apart from whatever item comes first
+		p.buf.WriteString(commentLines(root.Comments.Leading, 0, 0, 0))
+		p.buf.WriteString("\n")
+	}
+	p.body(root.Children, 0, nil)
+	p.buf.WriteString(commentLines(root.Comments.Dangling, lastLine(root.Children, 0), 0, 0))
+
+	out := bytes.TrimLeft(p.buf.Bytes(), "\n")
+	if len(out) == 0 {
+		return out
+	}
+	return append(bytes.TrimRight(out, "\n"), '\n')
+}
+
+// Fprint writes the canonical HCL form of root to w using the config
+func (c *Config) Fprint(w io.Writer, root *types.Root) error {
+	_, err := w.Write(c.Print(root))
+	return err
+}
+
+// PrintExpression renders a single expression as it would appear at the given
+// indentation level
+func PrintExpression(expr types.Expression, indent int) string {
+	p := &printer{config: &Config{}}
+	return p.expr(expr, indent)
+}
+
+type printer struct {
+	config *Config
+	buf    bytes.Buffer
+}
+
+func indentation(level int) string {
+	return strings.Repeat(indentUnit, level)
+}
+
+func (p *printer) body(children []types.Body, indent int, parent *types.Block) {
+	// Render attribute values up front so alignment groups know which values
+	// span multiple lines
+	values := make([]string, len(children))
+	// Inline comments before an attribute stay in front of its name
+	names := make([]string, len(children))
+	leading := make([][]types.Comment, len(children))
+	for i, child := range children {
+		if attr, ok := child.(*types.Attribute); ok {
+			values[i] = p.expr(attr.Value, indent)
+			var prefix string
+			prefix, leading[i] = inlineComments(attr.Comments.Leading, attr.Range.Start.Line)
+			names[i] = prefix + attr.Name
+		}
+	}
+	widths := p.alignmentWidths(children, names, values, parent)
+
+	for i := 0; i < len(children); i++ {
+		child := children[i]
+		if i > 0 {
+			p.buf.WriteString(strings.Repeat("\n", p.blankLines(parent, children[i-1], child)))
+		}
+
+		switch c := child.(type) {
+		case *types.Attribute:
+			value, moved := appendComments(values[i], c.Value, c.Comments.Trailing, c.Range.Start.Line)
+			p.buf.WriteString(commentLines(concat(leading[i], moved), 0, c.Range.Start.Line, indent))
+			p.buf.WriteString(indentation(indent))
+			p.buf.WriteString(names[i])
+			p.buf.WriteString(strings.Repeat(" ", widths[i]-len(names[i])))
+			p.buf.WriteString(" = ")
+			p.buf.WriteString(value)
+			p.buf.WriteString("\n")
+		case *types.Block:
+			p.block(c, indent)
+		case *types.FormatDirective:
+			p.buf.WriteString(commentLines(c.Comments.Leading, 0, c.Range.Start.Line, indent))
+			p.buf.WriteString(indentation(indent))
+			if source, last, ok := p.unformatted(children, i); ok {
+				p.buf.WriteString(source)
+				i = last
+			} else if c.Text != "" {
+				p.buf.WriteString(c.Text)
+			} else {
+				p.buf.WriteString("# ")
+				p.buf.WriteString(strings.TrimSpace(c.DirectiveType + " " + strings.Join(c.Parameters, " ")))
+			}
+			p.buf.WriteString("\n")
+		}
+	}
+}
+
+// unformatted returns the source of the region that children[i] turns
+// formatting off for, which ends with the next directive turning it on or
+// with the body, and the index of the last item in the region
+func (p *printer) unformatted(children []types.Body, i int) (string, int, bool) {
+	off, ok := children[i].(*types.FormatDirective)
+	if !ok || !off.FormattingOff() || p.config.Source == nil || !known(off.Range) {
+		return "", i, false
+	}
+
+	last := len(children) - 1
+	for j := i + 1; j < len(children); j++ {
+		if on, ok := children[j].(*types.FormatDirective); ok && on.FormattingOn() {
+			last = j
+			break
+		}
+	}
+
+	start, end := off.Range.Start.Byte, endByte(children[last])
+	if end < start || end > len(p.config.Source) {
+		return "", i, false
+	}
+	return strings.ReplaceAll(string(p.config.Source[start:end]), "\r\n", "\n"), last, true
+}
+
+func (p *printer) block(block *types.Block, indent int) {
+	p.buf.WriteString(commentLines(block.Comments.Leading, 0, block.Range.Start.Line, indent))
+
+	p.buf.WriteString(indentation(indent))
+	p.buf.WriteString(block.Type)
+	for _, label := range block.Labels {
+		p.buf.WriteString(` "`)
+		p.buf.WriteString(labelEscaper.Replace(label))
+		p.buf.WriteString(`"`)
+	}
+
+	if len(block.Children) == 0 && len(block.Comments.Dangling) == 0 {
+		p.buf.WriteString(" {}")
+		p.buf.WriteString(trailingComments(block.Comments.Trailing))
+		p.buf.WriteString("\n")
+		return
+	}
+
+	// Comments found after the closing brace go back there, the others
+	// followed the opening one
+	var opening, closing []types.Comment
+	for _, comment := range block.Comments.Trailing {
+		if known(comment.Range) && comment.Range.Start.Line != block.Range.Start.Line {
+			closing = append(closing, comment)
+		} else {
+			opening = append(opening, comment)
+		}
+	}
+
+	p.buf.WriteString(" {")
+	p.buf.WriteString(trailingComments(opening))
+	p.buf.WriteString("\n")
+	p.body(block.Children, indent+1, block)
+	p.buf.WriteString(commentLines(block.Comments.Dangling, lastLine(block.Children, block.Range.Start.Line), 0, indent+1))
+	p.buf.WriteString(indentation(indent))
+	p.buf.WriteString("}")
+	p.buf.WriteString(trailingComments(closing))
+	p.buf.WriteString("\n")
+}
+
+// commentLines renders comments on lines of their own. A blank line is kept
+// wherever the source had one between them or between them and the code on
+// the after and before lines, which are zero when unknown.
+func commentLines(comments []types.Comment, after, before int, indent int) string {
+	var b strings.Builder
+	previous := after
+	for _, comment := range comments {
+		if previous > 0 && known(comment.Range) && comment.Range.Start.Line-previous > 1 {
+			b.WriteString("\n")
+		}
+		b.WriteString(indentation(indent))
+		b.WriteString(comment.Text)
+		b.WriteString("\n")
+		previous = comment.Range.End.Line
+	}
+	if len(comments) > 0 && previous > 0 && before > 0 && before-previous > 1 {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// trailingComments renders comments that follow code on the same line
+func trailingComments(comments []types.Comment) string {
+	var b strings.Builder
+	for _, comment := range comments {
+		b.WriteString(" ")
+		b.WriteString(comment.Text)
+	}
+	return b.String()
+}
+
+// leadingComments renders block comments that go before code on the same line
+func leadingComments(comments []types.Comment) string {
+	var b strings.Builder
+	for _, comment := range comments {
+		b.WriteString(comment.Text)
+		b.WriteString(" ")
+	}
+	return b.String()
+}
+
+// inlineComments splits the leading comments of a node that starts on line
+// into the text of the ones that end on that line, which go right before the
+// node, and the others
+func inlineComments(leading []types.Comment, line int) (string, []types.Comment) {
+	var inline, rest []types.Comment
+	for _, comment := range leading {
+		if known(comment.Range) && comment.Range.End.Line == line && isInline(comment) {
+			inline = append(inline, comment)
+		} else {
+			rest = append(rest, comment)
+		}
+	}
+	return leadingComments(inline), rest
+}
+
+// isInline reports whether a comment is a block comment on a single line,
+// which code can follow
+func isInline(comment types.Comment) bool {
+	return strings.HasPrefix(comment.Text, "/*") && !strings.Contains(comment.Text, "\n")
+}
+
+// inlineable reports whether every comment attached to the items of a list
+// can stay with its item on a single line
+func inlineable(itemComments ...types.Comments) bool {
+	for _, comments := range itemComments {
+		if len(comments.Dangling) > 0 {
+			return false
+		}
+		for _, comment := range concat(comments.Leading, comments.Trailing) {
+			if !isInline(comment) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// appendComments places trailing comments at the end of the first or the last
+// line of a rendered value, whichever they followed in the source. The
+// comments that can't follow a heredoc marker are returned to be printed
+// above the value instead.
+func appendComments(value string, expr types.Expression, comments []types.Comment, startLine int) (string, []types.Comment) {
+	if len(comments) == 0 {
+		return value, nil
+	}
+	if _, ok := expr.(*types.HeredocExpr); ok || endsWithHeredoc(expr) && !strings.HasSuffix(value, ",") {
+		return value, comments
+	}
+
+	newline := strings.Index(value, "\n")
+	if newline < 0 {
+		return value + trailingComments(comments), nil
+	}
+
+	var first, last, moved []types.Comment
+	for _, comment := range comments {
+		if !known(comment.Range) || comment.Range.Start.Line != startLine {
+			last = append(last, comment)
+		} else if heredocOpener.MatchString(value[:newline]) {
+			moved = append(moved, comment)
+		} else {
+			first = append(first, comment)
+		}
+	}
+	return value[:newline] + trailingComments(first) + value[newline:] + trailingComments(last), moved
+}
+
+func concat(lists ...[]types.Comment) []types.Comment {
+	var result []types.Comment
+	for _, list := range lists {
+		result = append(result, list...)
+	}
+	return result
+}
+
+// lastLine returns the line the last of the children ends on, or line when
+// there are none
+func lastLine(children []types.Body, line int) int {
+	if len(children) == 0 {
+		return line
+	}
+	return endOf(children[len(children)-1])
+}
+
+// alignmentWidths returns the padded name width of each attribute so the
+// equals signs of consecutive single-line attributes line up
+func (p *printer) alignmentWidths(children []types.Body, names, values []string, parent *types.Block) []int {
+	widths := make([]int, len(children))
+	start := -1
+
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		width := 0
+		for i := start; i < end; i++ {
+			width = max(width, len(names[i]))
+		}
+		for i := start; i < end; i++ {
+			widths[i] = width
+		}
+		start = -1
+	}
+
+	for i, child := range children {
+		attr, ok := child.(*types.Attribute)
+		if !ok {
+			flush(i)
+			continue
+		}
+		if start >= 0 && (len(attr.Comments.Leading) > 0 || p.blankLines(parent, children[i-1], child) > 0) {
+			flush(i)
+		}
+		if start < 0 {
+			start = i
+		}
+		if strings.Contains(values[i], "\n") {
+			flush(i + 1)
+		}
+	}
+	flush(len(children))
+
+	return widths
+}
+
+// blankLines returns the number of blank lines printed between two sibling
+// items. Top level blocks are always separated.
+func (p *printer) blankLines(parent *types.Block, previous, current types.Body) int {
+	lines := -1
+	if p.config.BlankLines != nil {
+		lines = p.config.BlankLines(parent, previous, current)
+	}
+	if lines < 0 {
+		lines = SourceBlankLines(previous, current)
+	}
+	// A directive stays next to the block it precedes
+	if parent == nil && (isBlock(previous) || (isBlock(current) && !isDirective(previous))) {
+		lines = max(lines, 1)
+	}
+	return lines
+}
+
+// SourceBlankLines returns the number of blank lines kept between two items
+// based on their source positions, which is at most one
+func SourceBlankLines(previous, current types.Body) int {
+	end, start := endOf(previous), startOf(current)
+	if end <= 0 || start <= 0 {
+		return 0
+	}
+	if start-end > 1 {
+		return 1
+	}
+	return 0
+}
+
+func isBlock(item types.Body) bool {
+	_, ok := item.(*types.Block)
+	return ok
+}
+
+func isDirective(item types.Body) bool {
+	_, ok := item.(*types.FormatDirective)
+	return ok
+}
+
+func bodyComments(item types.Body) types.Comments {
+	switch i := item.(type) {
+	case *types.Block:
+		return i.Comments
+	case *types.Attribute:
+		return i.Comments
+	case *types.FormatDirective:
+		return i.Comments
+	}
+	return types.Comments{}
+}
+
+func bodyRange(item types.Body) hcl.Range {
+	switch i := item.(type) {
+	case *types.Block:
+		return i.Range
+	case *types.Attribute:
+		return i.Range
+	case *types.FormatDirective:
+		return i.Range
+	}
+	return hcl.Range{}
+}
+
+// endByte returns the offset an item ends at, including its trailing comments
+func endByte(item types.Body) int {
+	end := bodyRange(item).End.Byte
+	for _, comment := range bodyComments(item).Trailing {
+		end = max(end, comment.Range.End.Byte)
+	}
+	return end
+}
+
+// startOf returns the line an item starts on, including its leading comments
+func startOf(item types.Body) int {
+	return firstLine(bodyRange(item), bodyComments(item).Leading)
+}
+
+// endOf returns the line an item ends on, including its trailing comments
+func endOf(item types.Body) int {
+	return finalLine(bodyRange(item), bodyComments(item).Trailing)
+}
+
+// firstLine returns the first known line of a node and the comments before it
+func firstLine(rng hcl.Range, leading []types.Comment) int {
+	line := 0
+	if known(rng) {
+		line = rng.Start.Line
+	}
+	if len(leading) > 0 && known(leading[0].Range) && (line == 0 || leading[0].Range.Start.Line < line) {
+		line = leading[0].Range.Start.Line
+	}
+	return line
+}
+
+// finalLine returns the last known line of a node and the comments after it
+func finalLine(rng hcl.Range, trailing []types.Comment) int {
+	line := 0
+	if known(rng) {
+		line = rng.End.Line
+	}
+	for _, comment := range trailing {
+		if known(comment.Range) {
+			line = max(line, comment.Range.End.Line)
+		}
+	}
+	return line
+}
+
+func known(r hcl.Range) bool {
+	return r.Start.Line > 0
+}
+
+func multiline(r hcl.Range) bool {
+	return known(r) && r.End.Line > r.Start.Line
+}
+
+// startsAfter reports whether expr starts on a line after the given one
+func startsAfter(expr types.Expression, line int) bool {
+	if expr == nil || line <= 0 {
+		return false
+	}
+	r := expr.Range()
+	return known(r) && r.Start.Line > line
+}
+
+func endLine(expr types.Expression) int {
+	if expr == nil {
+		return 0
+	}
+	return expr.Range().End.Line
+}
+
+func (p *printer) expr(expr types.Expression, indent int) string {
+	switch e := expr.(type) {
+	case nil:
+		panic("printer: missing expression")
+	case *types.LiteralValue:
+		return literal(e)
+	case *types.ReferenceExpr:
+		return reference(e.Parts)
+	case *types.ObjectExpr:
+		return p.object(e, indent)
+	case *types.ArrayExpr:
+		return p.sequence(e.Items, e.ItemComments, e.Comments, e.ExprRange, indent)
+	case *types.TupleExpr:
+		return p.sequence(e.Expressions, nil, types.Comments{}, e.ExprRange, indent)
+	case *types.FunctionCallExpr:
+		return p.functionCall(e, indent)
+	case *types.TemplateExpr:
+		return `"` + p.templateParts(e.Parts, indent, quotedEscaper) + `"`
+	case *types.HeredocExpr:
+		return p.heredoc(e, indent)
+	case *types.ConditionalExpr:
+		return p.conditional(e, indent)
+	case *types.BinaryExpr:
+		return p.binary(e, indent)
+	case *types.UnaryExpr:
+		return e.Operator + p.expr(e.Expr, indent)
+	case *types.ParenExpr:
+		return p.paren(e, indent)
+	case *types.ForArrayExpr:
+		return p.forExpr("[", "]", e.KeyVar, e.ValueVar, e.Collection, nil, e.ThenValueExpr, e.Condition, false, e.ExprRange, indent)
+	case *types.ForMapExpr:
+		return p.forExpr("{", "}", e.KeyVar, e.ValueVar, e.Collection, e.ThenKeyExpr, e.ThenValueExpr, e.Condition, e.Grouped, e.ExprRange, indent)
+	case *types.IndexExpr:
+		return p.expr(e.Collection, indent) + afterHeredoc(e.Collection, indent) + "[" + p.expr(e.Key, indent) + "]"
+	case *types.SplatExpr:
+		if e.AttrOnly {
+			return p.expr(e.Source, indent) + afterHeredoc(e.Source, indent) + ".*" + p.splatEach(e.Each, indent)
+		}
+		return p.expr(e.Source, indent) + afterHeredoc(e.Source, indent) + "[*]" + p.splatEach(e.Each, indent)
+	case *types.RelativeTraversalExpr:
+		return p.expr(e.Source, indent) + afterHeredoc(e.Source, indent) + p.traversal(e.Traversal, indent)
+	case *types.TemplateForDirective, *types.TemplateIfDirective:
+		return `"` + p.templateParts([]types.Expression{e}, indent, quotedEscaper) + `"`
+	case *types.ObjectItem:
+		return p.objectKey(e.Key, indent) + " = " + p.expr(e.Value, indent)
+	default:
+		return fmt.Sprintf("%v", expr)
+	}
+}
+
+func literal(l *types.LiteralValue) string {
+	if l.Token != "" {
+		return l.Token
+	}
+	switch v := l.Value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return `"` + quotedEscaper.Replace(v) + `"`
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func reference(parts []string) string {
+	var b strings.Builder
+	for i, part := range parts {
+		if i > 0 && !strings.HasPrefix(part, "[") {
+			b.WriteString(".")
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+func (p *printer) traversal(elems []types.TraversalElem, indent int) string {
+	var b strings.Builder
+	for _, elem := range elems {
+		switch {
+		case elem.Index != nil:
+			b.WriteString("[" + p.expr(elem.Index, indent) + "]")
+		case elem.Type == "index" && strings.HasPrefix(elem.Name, "["):
+			b.WriteString(elem.Name)
+		case elem.Type == "index":
+			b.WriteString("[" + elem.Name + "]")
+		default:
+			b.WriteString("." + elem.Name)
+		}
+	}
+	return b.String()
+}
+
+// splatEach renders the traversal applied to each element of a splat
+func (p *printer) splatEach(each types.Expression, indent int) string {
+	switch e := each.(type) {
+	case *types.AnonSymbolExpr:
+		return ""
+	case *types.RelativeTraversalExpr:
+		return p.splatEach(e.Source, indent) + p.traversal(e.Traversal, indent)
+	case *types.IndexExpr:
+		return p.splatEach(e.Collection, indent) + "[" + p.expr(e.Key, indent) + "]"
+	default:
+		return ""
+	}
+}
+
+// objectKey prints the key of an object item. Only a bare name, a string or an
+// expression in parentheses can be a key, the other expressions get wrapped.
+func (p *printer) objectKey(key types.Expression, indent int) string {
+	switch k := key.(type) {
+	case *types.ReferenceExpr:
+		if len(k.Parts) == 1 {
+			return reference(k.Parts)
+		}
+	case *types.LiteralValue, *types.TemplateExpr, *types.ParenExpr:
+		return p.expr(key, indent)
+	}
+	return "(" + p.expr(key, indent) + ")"
+}
+
+func (p *printer) object(o *types.ObjectExpr, indent int) string {
+	if len(o.Items) == 0 && o.Comments.Empty() {
+		return "{}"
+	}
+
+	keys := make([]string, len(o.Items))
+	values := make([]string, len(o.Items))
+	multi := !known(o.ExprRange) || multiline(o.ExprRange) || !o.Comments.Empty()
+	for i, item := range o.Items {
+		keys[i] = p.objectKey(item.Key, indent+1)
+		values[i] = p.expr(item.Value, indent+1)
+		if !inlineable(item.Comments) || strings.Contains(values[i], "\n") {
+			multi = true
+		}
+	}
+
+	if !multi {
+		items := make([]string, len(o.Items))
+		for i, item := range o.Items {
+			items[i] = leadingComments(item.Comments.Leading) + keys[i] + " = " + values[i] + trailingComments(item.Comments.Trailing)
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	}
+
+	// Inline comments before an item stay in front of its key
+	leading := make([][]types.Comment, len(o.Items))
+	for i, item := range o.Items {
+		var prefix string
+		prefix, leading[i] = inlineComments(item.Comments.Leading, item.ExprRange.Start.Line)
+		keys[i] = prefix + keys[i]
+	}
+
+	// Align the equals signs of consecutive single-line items
+	widths := make([]int, len(o.Items))
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		width := 0
+		for i := start; i < end; i++ {
+			width = max(width, len(keys[i]))
+		}
+		for i := start; i < end; i++ {
+			widths[i] = width
+		}
+		start = -1
+	}
+	for i, item := range o.Items {
+		if start >= 0 && (len(item.Comments.Leading) > 0 || objectItemGap(o.Items[i-1], item)) {
+			flush(i)
+		}
+		if start < 0 {
+			start = i
+		}
+		if strings.Contains(values[i], "\n") {
+			flush(i + 1)
+		}
+	}
+	flush(len(o.Items))
+
+	var b strings.Builder
+	b.WriteString("{")
+	b.WriteString(trailingComments(o.Comments.Trailing))
+	b.WriteString("\n")
+	last := o.ExprRange.Start.Line
+	for i, item := range o.Items {
+		if i > 0 && objectItemGap(o.Items[i-1], item) {
+			b.WriteString("\n")
+		}
+		value, moved := appendComments(values[i], item.Value, item.Comments.Trailing, item.ExprRange.Start.Line)
+		b.WriteString(commentLines(concat(leading[i], moved), 0, item.ExprRange.Start.Line, indent+1))
+		b.WriteString(indentation(indent + 1))
+		b.WriteString(keys[i])
+		b.WriteString(strings.Repeat(" ", widths[i]-len(keys[i])))
+		b.WriteString(" = ")
+		b.WriteString(value)
+		b.WriteString("\n")
+		last = finalLine(item.ExprRange, item.Comments.Trailing)
+	}
+	b.WriteString(commentLines(o.Comments.Dangling, last, 0, indent+1))
+	b.WriteString(indentation(indent) + "}")
+	return b.String()
+}
+
+// objectItemGap reports whether the source had a blank line between two object items
+func objectItemGap(previous, current types.ObjectItem) bool {
+	end := finalLine(previous.ExprRange, previous.Comments.Trailing)
+	start := firstLine(current.ExprRange, current.Comments.Leading)
+	return end > 0 && start > 0 && start-end > 1
+}
+
+func (p *printer) sequence(items []types.Expression, itemComments []types.Comments, comments types.Comments, rng hcl.Range, indent int) string {
+	if len(items) == 0 && comments.Empty() {
+		return "[]"
+	}
+
+	multi := multiline(rng) || !comments.Empty() || !inlineable(itemComments...)
+	rendered := make([]string, len(items))
+	for i, item := range items {
+		rendered[i] = p.expr(item, indent+1)
+		if !known(rng) && strings.Contains(rendered[i], "\n") {
+			multi = true
+		}
+	}
+
+	if !multi {
+		return "[" + p.inline(items, itemComments, indent) + "]"
+	}
+
+	var b strings.Builder
+	b.WriteString("[")
+	b.WriteString(trailingComments(comments.Trailing))
+	b.WriteString("\n")
+	last := rng.Start.Line
+	for i, item := range rendered {
+		var itemRange hcl.Range
+		if items[i] != nil {
+			itemRange = items[i].Range()
+		}
+		var attached types.Comments
+		if i < len(itemComments) {
+			attached = itemComments[i]
+		}
+		// A heredoc closes on its own line, so a comma after it goes on the next
+		// one and the last item goes without
+		switch {
+		case !endsWithHeredoc(items[i]):
+			item += ","
+		case i < len(rendered)-1:
+			item += afterHeredoc(items[i], indent+1) + ","
+		}
+		b.WriteString(itemLines(item, items[i], attached, indent+1))
+		last = finalLine(itemRange, attached.Trailing)
+	}
+	b.WriteString(commentLines(comments.Dangling, last, 0, indent+1))
+	b.WriteString(indentation(indent) + "]")
+	return b.String()
+}
+
+func (p *printer) functionCall(f *types.FunctionCallExpr, indent int) string {
+	if len(f.Args) == 0 && f.Comments.Empty() {
+		return f.Name + "()"
+	}
+
+	// Arguments go on their own lines when the source started them on a new
+	// line or their comments need lines of their own
+	multi := len(f.Args) == 0 || multiline(f.ExprRange) && startsAfter(f.Args[0], f.ExprRange.Start.Line) ||
+		!f.Comments.Empty() || !inlineable(f.ArgComments...)
+
+	final := ""
+	if f.ExpandFinal {
+		final = "..."
+	}
+
+	if !multi {
+		final = afterHeredoc(f.Args[len(f.Args)-1], indent) + final
+		return f.Name + "(" + p.inline(f.Args, f.ArgComments, indent) + final + ")"
+	}
+
+	var b strings.Builder
+	b.WriteString(f.Name + "(")
+	b.WriteString(trailingComments(f.Comments.Trailing))
+	b.WriteString("\n")
+	last := f.ExprRange.Start.Line
+	for i, arg := range f.Args {
+		value := p.expr(arg, indent+1)
+		if i < len(f.Args)-1 {
+			value += afterHeredoc(arg, indent+1) + ","
+		} else if final != "" {
+			value += afterHeredoc(arg, indent+1) + final
+		}
+		var attached types.Comments
+		if i < len(f.ArgComments) {
+			attached = f.ArgComments[i]
+		}
+		b.WriteString(itemLines(value, arg, attached, indent+1))
+		last = finalLine(arg.Range(), attached.Trailing)
+	}
+	b.WriteString(commentLines(f.Comments.Dangling, last, 0, indent+1))
+	b.WriteString(indentation(indent) + ")")
+	return b.String()
+}
+
+// itemLines prints an item of a list that spans several lines, rendered as
+// value, on lines of its own with its comments
+func itemLines(value string, item types.Expression, comments types.Comments, indent int) string {
+	var line int
+	if item != nil {
+		line = item.Range().Start.Line
+	}
+	prefix, leading := inlineComments(comments.Leading, line)
+	value, moved := appendComments(value, item, comments.Trailing, line)
+	return commentLines(concat(leading, moved), 0, line, indent) + indentation(indent) + prefix + value + "\n"
+}
+
+// binary prints a binary expression with the comments between its operands.
+// A comment that can't be followed by code goes after the operator and moves
+// the right operand to the next line.
+func (p *printer) binary(b *types.BinaryExpr, indent int) string {
+	var inline, ending []types.Comment
+	for _, comment := range b.LeftComments.Trailing {
+		if isInline(comment) {
+			inline = append(inline, comment)
+		} else {
+			ending = append(ending, comment)
+		}
+	}
+	left := p.expr(b.Left, indent) + trailingComments(inline) + separator(b.Left, indent) + b.Operator + trailingComments(ending)
+
+	var rightLine int
+	if b.Right != nil {
+		rightLine = b.Right.Range().Start.Line
+	}
+	prefix, leading := inlineComments(b.RightComments.Leading, rightLine)
+	right := prefix + p.expr(b.Right, indent)
+	if len(ending) > 0 || len(leading) > 0 || startsAfter(b.Right, endLine(b.Left)) {
+		return left + "\n" + commentLines(leading, 0, 0, indent) + indentation(indent) + right
+	}
+	return left + " " + right
+}
+
+// paren prints an expression in parentheses with its comments, on lines of
+// its own when the source started it on a new line or a comment needs one
+func (p *printer) paren(e *types.ParenExpr, indent int) string {
+	var line int
+	if e.Expression != nil {
+		line = e.Expression.Range().Start.Line
+	}
+	prefix, leading := inlineComments(e.Comments.Leading, line)
+	multi := multiline(e.ExprRange) && startsAfter(e.Expression, e.ExprRange.Start.Line) ||
+		len(leading) > 0 || len(e.Comments.Dangling) > 0 || !inlineable(types.Comments{Trailing: e.Comments.Trailing})
+	if !multi {
+		return "(" + prefix + p.expr(e.Expression, indent) + trailingComments(e.Comments.Trailing) + afterHeredoc(e.Expression, indent) + ")"
+	}
+
+	value, moved := appendComments(p.expr(e.Expression, indent+1), e.Expression, e.Comments.Trailing, line)
+	var b strings.Builder
+	b.WriteString("(\n")
+	b.WriteString(commentLines(concat(leading, moved), 0, line, indent+1))
+	b.WriteString(indentation(indent+1) + prefix + value + "\n")
+	b.WriteString(commentLines(e.Comments.Dangling, finalLine(e.Expression.Range(), e.Comments.Trailing), 0, indent+1))
+	b.WriteString(indentation(indent) + ")")
+	return b.String()
+}
+
+func (p *printer) conditional(c *types.ConditionalExpr, indent int) string {
+	var b strings.Builder
+	b.WriteString(p.expr(c.Condition, indent))
+	b.WriteString(separator(c.Condition, indent) + "?")
+	if startsAfter(c.TrueExpr, endLine(c.Condition)) {
+		b.WriteString("\n" + indentation(indent))
+	} else {
+		b.WriteString(" ")
+	}
+	b.WriteString(p.expr(c.TrueExpr, indent))
+	b.WriteString(separator(c.TrueExpr, indent) + ":")
+	if startsAfter(c.FalseExpr, endLine(c.TrueExpr)) {
+		b.WriteString("\n" + indentation(indent))
+	} else {
+		b.WriteString(" ")
+	}
+	b.WriteString(p.expr(c.FalseExpr, indent))
+	return b.String()
+}
+
+func (p *printer) forExpr(open, close, keyVar, valueVar string, collection, thenKey, thenValue, condition types.Expression, grouped bool, rng hcl.Range, indent int) string {
+	multi := multiline(rng)
+	inner := indent
+	if multi {
+		inner = indent + 1
+	}
+
+	var b strings.Builder
+	b.WriteString(open)
+	if multi {
+		b.WriteString("\n" + indentation(inner))
+	} else if open == "{" {
+		b.WriteString(" ")
+	}
+
+	b.WriteString("for ")
+	if keyVar != "" {
+		b.WriteString(keyVar + ", ")
+	}
+	b.WriteString(valueVar + " in ")
+	b.WriteString(p.expr(collection, inner))
+	b.WriteString(separator(collection, inner) + ":")
+
+	result := thenValue
+	if thenKey != nil {
+		result = thenKey
+	}
+	if multi && startsAfter(result, endLine(collection)) {
+		b.WriteString("\n" + indentation(inner))
+	} else {
+		b.WriteString(" ")
+	}
+
+	if thenKey != nil {
+		b.WriteString(p.expr(thenKey, inner) + " => ")
+	}
+	b.WriteString(p.expr(thenValue, inner))
+	if grouped {
+		b.WriteString("...")
+	}
+
+	if condition != nil {
+		if multi && startsAfter(condition, endLine(thenValue)) {
+			b.WriteString("\n" + indentation(inner))
+		} else {
+			b.WriteString(" ")
+		}
+		b.WriteString("if " + p.expr(condition, inner))
+	}
+
+	if multi {
+		b.WriteString("\n" + indentation(indent))
+	} else if close == "}" {
+		b.WriteString(" ")
+	}
+	b.WriteString(close)
+	return b.String()
+}
+
+// templateParts renders the inside of a template, escaping literal chunks with
+// the escaper of the surrounding string form
+func (p *printer) templateParts(parts []types.Expression, indent int, escaper *strings.Replacer) string {
+	var b strings.Builder
+	for _, part := range parts {
+		switch t := part.(type) {
+		case *types.LiteralValue:
+			if s, ok := t.Value.(string); ok {
+				b.WriteString(escaper.Replace(s))
+			} else {
+				b.WriteString(literal(t))
+			}
+		case *types.TemplateForDirective:
+			vars := t.ValueVar
+			if t.KeyVar != "" {
+				vars = t.KeyVar + ", " + t.ValueVar
+			}
+			b.WriteString(directiveTag("for "+vars+" in "+p.expr(t.CollExpr, indent), t.Strip))
+			b.WriteString(p.templateParts(t.Content, indent, escaper))
+			b.WriteString(directiveTag("endfor", t.EndStrip))
+		case *types.TemplateIfDirective:
+			b.WriteString(directiveTag("if "+p.expr(t.Condition, indent), t.Strip))
+			b.WriteString(p.templateParts(t.TrueExpr, indent, escaper))
+			if t.Else || len(t.FalseExpr) > 0 {
+				b.WriteString(directiveTag("else", t.ElseStrip))
+				b.WriteString(p.templateParts(t.FalseExpr, indent, escaper))
+			}
+			b.WriteString(directiveTag("endif", t.EndStrip))
+		default:
+			b.WriteString("${" + p.expr(part, indent) + "}")
+		}
+	}
+	return b.String()
+}
+
+// directiveTag renders a template directive tag with its strip markers
+func directiveTag(content string, strip types.TemplateStrip) string {
+	var b strings.Builder
+	b.WriteString("%{")
+	if strip.Left {
+		b.WriteString("~")
+	}
+	b.WriteString(" " + content + " ")
+	if strip.Right {
+		b.WriteString("~")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// endsWithHeredoc reports whether an expression is printed ending with the
+// closing marker of a heredoc, which has to be alone on its line
+func endsWithHeredoc(expr types.Expression) bool {
+	switch e := expr.(type) {
+	case *types.HeredocExpr:
+		return true
+	case *types.BinaryExpr:
+		return endsWithHeredoc(e.Right)
+	case *types.UnaryExpr:
+		return endsWithHeredoc(e.Expr)
+	case *types.ConditionalExpr:
+		return endsWithHeredoc(e.FalseExpr)
+	}
+	return false
+}
+
+// afterHeredoc returns the line break that puts the token following an
+// expression ending with a heredoc on the next line, or nothing for the other
+// expressions
+func afterHeredoc(expr types.Expression, indent int) string {
+	if endsWithHeredoc(expr) {
+		return "\n" + indentation(indent)
+	}
+	return ""
+}
+
+// separator returns what goes between an expression and the operator after it
+func separator(expr types.Expression, indent int) string {
+	if endsWithHeredoc(expr) {
+		return afterHeredoc(expr, indent)
+	}
+	return " "
+}
+
+// inline prints expressions separated by commas on a single line with their
+// comments, as far as the heredocs among them allow
+func (p *printer) inline(exprs []types.Expression, comments []types.Comments, indent int) string {
+	var b strings.Builder
+	for i, expr := range exprs {
+		if i > 0 {
+			b.WriteString(afterHeredoc(exprs[i-1], indent) + ", ")
+		}
+		var attached types.Comments
+		if i < len(comments) {
+			attached = comments[i]
+		}
+		b.WriteString(leadingComments(attached.Leading) + p.expr(expr, indent) + trailingComments(attached.Trailing))
+	}
+	return b.String()
+}
+
+// heredoc prints the content of a heredoc as written, or renders its parts when
+// the content isn't known
+func (p *printer) heredoc(h *types.HeredocExpr, indent int) string {
+	marker := h.Marker
+	if marker == "" {
+		marker = "EOT"
+	}
+
+	content := h.Content
+	if content == "" {
+		content = p.templateParts(h.Parts, indent, heredocEscaper)
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString("<<")
+	if h.Indented {
+		b.WriteString("-")
+	}
+	b.WriteString(marker + "\n")
+
+	if !h.Indented {
+		b.WriteString(content)
+		b.WriteString(marker)
+		return b.String()
+	}
+
+	// Indented heredocs have their common indentation stripped by the parser,
+	// so they get re-indented one level deeper than the line they start on.
+	// Lines that are only whitespace keep it, since <<- doesn't strip them.
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if strings.TrimSpace(line) != "" {
+			b.WriteString(indentation(indent + 1))
+		}
+		b.WriteString(line)
+	}
+	b.WriteString(indentation(indent) + marker)
+	return b.String()
+}
@@ -0,0 +1,274 @@
+package printer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/vahid-haghighat/terralint/parser"
+	"github.com/vahid-haghighat/terralint/parser/types"
+)
+
+// TestCase represents a test case for the printer
+type TestCase struct {
+	Name     string
+	Input    string
+	Expected string
+}
+
+func TestPrint(t *testing.T) {
+	testCases := []TestCase{
+		{
+			Name:     "Aligns attributes",
+			Input:    "resource \"a\" \"b\" {\n  name = \"x\"\n  instance_type=\"t2.micro\"\n}\n",
+			Expected: "resource \"a\" \"b\" {\n  name          = \"x\"\n  instance_type = \"t2.micro\"\n}\n",
+		},
+		{
+			Name:     "Blank lines split alignment groups",
+			Input:    "locals {\n  a = 1\n\n\n  bbb = 2\n}\n",
+			Expected: "locals {\n  a = 1\n\n  bbb = 2\n}\n",
+		},
+		{
+			Name:     "Separates top level blocks",
+			Input:    "variable \"a\" {}\nvariable \"b\" {\n type = string\n}\n",
+			Expected: "variable \"a\" {}\n\nvariable \"b\" {\n  type = string\n}\n",
+		},
+		{
+			Name:     "Keeps comments",
+			Input:    "# leading\nlocals {\n  a = 1 # trailing\n}\n",
+			Expected: "# leading\nlocals {\n  a = 1 # trailing\n}\n",
+		},
+		{
+			Name:     "Keeps comments in every position",
+			Input:    "/* header */\n\n// leading\nlocals { # open\n  a = [ # bracket\n    1, # one\n    # two\n    2,\n    # last\n  ]\n  b = {\n    c = 1 // c\n    # end of object\n  }\n\n  # end of block\n} # close\n# end of file\n",
+			Expected: "/* header */\n\n// leading\nlocals { # open\n  a = [ # bracket\n    1, # one\n    # two\n    2,\n    # last\n  ]\n  b = {\n    c = 1 // c\n    # end of object\n  }\n\n  # end of block\n} # close\n# end of file\n",
+		},
+		{
+			Name:     "Keeps inline comments next to their items",
+			Input:    "locals {\n  a = [1, /* one */ 2]\n  b = []\n  c = { d = 1, /* e */ e = 2 }\n}\n",
+			Expected: "locals {\n  a = [1, /* one */ 2]\n  b = []\n  c = { d = 1, /* e */ e = 2 }\n}\n",
+		},
+		{
+			Name:     "Keeps comments inside calls, operations and parentheses",
+			Input:    "locals {\n  a = foo(1, /* x */ 2)\n  b = foo(\n    1, # one\n    2\n  )\n  c = 1 + /* r */ 2\n  d = (/* in */ a)\n  e = {\n    /* k */ key = 1\n    other = 2\n  }\n}\n",
+			Expected: "locals {\n  a = foo(1, /* x */ 2)\n  b = foo(\n    1, # one\n    2\n  )\n  c = 1 + /* r */ 2\n  d = (/* in */ a)\n  e = {\n    /* k */ key = 1\n    other       = 2\n  }\n}\n",
+		},
+		{
+			Name:     "Keeps inline comments before attributes",
+			Input:    "resource \"a\" \"b\" {\n  /* c */ acl = \"x\"\n  name = \"y\"\n}\n",
+			Expected: "resource \"a\" \"b\" {\n  /* c */ acl = \"x\"\n  name        = \"y\"\n}\n",
+		},
+		{
+			Name:     "Keeps directives next to the block they precede",
+			Input:    "variable \"a\" {}\n// terralint-ignore format\nvariable \"b\" {}\n",
+			Expected: "variable \"a\" {}\n\n// terralint-ignore format\nvariable \"b\" {}\n",
+		},
+		{
+			Name:     "Escapes strings",
+			Input:    "locals {\n  a = \"quote \\\" and $${literal}\\n\"\n}\n",
+			Expected: "locals {\n  a = \"quote \\\" and $${literal}\\n\"\n}\n",
+		},
+		{
+			Name:     "Prints expressions",
+			Input:    "locals {\n  a = var.enabled ? -var.count : null\n  b = merge(var.maps...)\n  c = \"${var.prefix}-name\"\n}\n",
+			Expected: "locals {\n  a = var.enabled ? -var.count : null\n  b = merge(var.maps...)\n  c = \"${var.prefix}-name\"\n}\n",
+		},
+		{
+			Name:     "Keeps multi-line function arguments",
+			Input:    "locals {\n  a = merge(\n  var.a,\n    var.b\n  )\n}\n",
+			Expected: "locals {\n  a = merge(\n    var.a,\n    var.b\n  )\n}\n",
+		},
+		{
+			Name:     "Keeps single-line collections",
+			Input:    "locals {\n  a = {x=1,y=[1,2]}\n  b = [\n    1,\n    2\n  ]\n}\n",
+			Expected: "locals {\n  a = { x = 1, y = [1, 2] }\n  b = [\n    1,\n    2,\n  ]\n}\n",
+		},
+		{
+			Name:     "Prints grouped for expressions",
+			Input:    "locals {\n  a = {for k, v in var.m : v => k...}\n}\n",
+			Expected: "locals {\n  a = { for k, v in var.m : v => k... }\n}\n",
+		},
+		{
+			Name:     "Prints template directives",
+			Input:    "locals {\n  a = \"x%{if b~} y %{~else}%{endif} ${c}\"\n  b = \"%{for k, v in m}${k}%{endfor~} \"\n}\n",
+			Expected: "locals {\n  a = \"x%{ if b ~} y %{~ else }%{ endif } ${c}\"\n  b = \"%{ for k, v in m }${k}%{ endfor ~} \"\n}\n",
+		},
+		{
+			Name:     "Keeps heredoc markers and content",
+			Input:    "locals {\n  a = <<POLICY\n{ \"arn\": \"${var.arn}\", \"user\": \"$${aws:username}\" }\nPOLICY\n  b = <<-EOT\n      %{for x in var.xs~}\n        ${x}\n   \n      %{endfor~}\n    EOT\n}\n",
+			Expected: "locals {\n  a = <<POLICY\n{ \"arn\": \"${var.arn}\", \"user\": \"$${aws:username}\" }\nPOLICY\n  b = <<-EOT\n    %{for x in var.xs~}\n      ${x}\n   \n    %{endfor~}\n  EOT\n}\n",
+		},
+		{
+			Name:     "Prints splats and traversals",
+			Input:    "locals {\n  a = aws_instance.web.*.id\n  b = aws_instance.web[*].disk[0].id\n  c = sort(var.zones)[0]\n  d = (var.a).tags[\"Name\"]\n  e = { (var.key) = 1 }\n}\n",
+			Expected: "locals {\n  a = aws_instance.web.*.id\n  b = aws_instance.web[*].disk[0].id\n  c = sort(var.zones)[0]\n  d = (var.a).tags[\"Name\"]\n  e = { (var.key) = 1 }\n}\n",
+		},
+		{
+			Name:     "Keeps object keys unambiguous",
+			Input:    "locals {\n  a = { \"${var.x}\" = 2, (var.y) = 3, \"z\" = 4, w = 5 }\n}\n",
+			Expected: "locals {\n  a = { \"${var.x}\" = 2, (var.y) = 3, \"z\" = 4, w = 5 }\n}\n",
+		},
+		{
+			Name:     "Keeps numbers as written",
+			Input:    "locals {\n  huge     = 12345678901234567890\n  exponent = 1.23e45\n  trailing = 1.50\n  padded   = [007, 1e3, -2.0]\n}\n",
+			Expected: "locals {\n  huge     = 12345678901234567890\n  exponent = 1.23e45\n  trailing = 1.50\n  padded   = [007, 1e3, -2.0]\n}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			root, err := parser.ParseSource([]byte(tc.Input), "main.tf")
+			if err != nil {
+				t.Fatalf("Failed to parse input: %v", err)
+			}
+
+			if actual := string(Print(root)); actual != tc.Expected {
+				t.Errorf("Printed output mismatch:\nexpected:\n%s\ngot:\n%s", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+// TestPrintHeredocRoundTrip checks that the token after the closing marker of a
+// heredoc goes on the next line, so that the output parses again
+func TestPrintHeredocRoundTrip(t *testing.T) {
+	testCases := []TestCase{
+		{
+			Name:     "Function argument",
+			Input:    "locals {\n  a = trimspace(<<EOT\nhello\nEOT\n  )\n  b = format(<<EOT\n%s\nEOT\n  , var.x)\n}\n",
+			Expected: "locals {\n  a = trimspace(<<EOT\nhello\nEOT\n  )\n  b = format(<<EOT\n%s\nEOT\n  , var.x)\n}\n",
+		},
+		{
+			Name:     "List element",
+			Input:    "locals {\n  a = [<<EOT\none\nEOT\n  , \"two\"]\n  b = [\n    \"x\",\n    <<-EOT\n      y\n    EOT\n  ]\n}\n",
+			Expected: "locals {\n  a = [\n    <<EOT\none\nEOT\n    ,\n    \"two\",\n  ]\n  b = [\n    \"x\",\n    <<-EOT\n      y\n    EOT\n  ]\n}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			root, err := parser.ParseSource([]byte(tc.Input), "main.tf")
+			if err != nil {
+				t.Fatalf("Failed to parse input: %v", err)
+			}
+			printed := Print(root)
+			if string(printed) != tc.Expected {
+				t.Errorf("Printed output mismatch:\nexpected:\n%s\ngot:\n%s", tc.Expected, printed)
+			}
+
+			root, err = parser.ParseSource(printed, "main.tf")
+			if err != nil {
+				t.Fatalf("Failed to parse printed output: %v\n%s", err, printed)
+			}
+			if again := Print(root); string(again) != string(printed) {
+				t.Errorf("Printing is not stable:\nfirst:\n%s\nsecond:\n%s", printed, again)
+			}
+		})
+	}
+}
+
+func TestPrintFormattingOff(t *testing.T) {
+	input := "locals {\n  a=1\n  # terralint:format off\n  matrix = [\n    1, 0,\n    0, 1,\n  ] # identity\n  # terralint:format on\n  bb=2\n\n  # terralint:format off\n  c   =   3\n}\n"
+	expected := "locals {\n  a = 1\n  # terralint:format off\n  matrix = [\n    1, 0,\n    0, 1,\n  ] # identity\n  # terralint:format on\n  bb = 2\n\n  # terralint:format off\n  c   =   3\n}\n"
+
+	root, err := parser.ParseSource([]byte(input), "main.tf")
+	if err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+
+	config := &Config{Source: []byte(input)}
+	if actual := string(config.Print(root)); actual != expected {
+		t.Errorf("Printed output mismatch:\nexpected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+// fixtures are the parser test files that are valid HCL
+var fixtures = []string{
+	"../parser/test_files/simple_test.tf",
+	"../parser/test_files/comments_test.tf",
+	"../parser/test_files/directives_test.tf",
+	"../parser/test_files/traversals_test.tf",
+	"../parser/test_files/template_directives_test.tf",
+	"../parser/test_files/modules_test/main.tf",
+	"../parser/test_files/complex_terraform_split/01_complex_module.tf",
+	"../parser/test_files/complex_terraform_split/02_complex_resource.tf",
+	"../parser/test_files/complex_terraform_split/03_complex_locals.tf",
+	"../parser/test_files/complex_terraform_split/04_complex_data_source.tf",
+	"../parser/test_files/complex_terraform_split/05_complex_variable.tf",
+	"../parser/test_files/complex_terraform_split/06_complex_output.tf",
+	"../parser/test_files/complex_terraform_split/07_complex_provider.tf",
+	"../parser/test_files/complex_terraform_split/08_complex_terraform_config.tf",
+}
+
+// TestPrintIsStable checks that printing the printed output again changes nothing
+func TestPrintIsStable(t *testing.T) {
+	for _, file := range fixtures {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			root, err := parser.ParseTerraformFile(file)
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", file, err)
+			}
+			first := Print(root)
+
+			root, err = parser.ParseSource(first, file)
+			if err != nil {
+				t.Fatalf("Failed to parse printed output: %v\n%s", err, first)
+			}
+
+			if second := Print(root); string(first) != string(second) {
+				t.Errorf("Printing is not stable:\nfirst:\n%s\nsecond:\n%s", first, second)
+			}
+		})
+	}
+}
+
+// TestPrintMissingExpression checks that an attribute without a value isn't
+// printed as if it held null
+func TestPrintMissingExpression(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Printing an attribute without a value should panic")
+		}
+	}()
+	Print(&types.Root{Children: []types.Body{&types.Attribute{Name: "a"}}})
+}
+
+// TestPrintKeepsComments checks that every comment of a file survives printing
+func TestPrintKeepsComments(t *testing.T) {
+	for _, file := range fixtures {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", file, err)
+			}
+			root, err := parser.ParseSource(content, file)
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", file, err)
+			}
+
+			expected, actual := commentTexts(content), commentTexts(Print(root))
+			if !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Comments were lost or changed:\nexpected: %q\ngot: %q", expected, actual)
+			}
+		})
+	}
+}
+
+// commentTexts returns the text of every comment in the source, sorted
+func commentTexts(content []byte) []string {
+	tokens, _ := hclsyntax.LexConfig(content, "", hcl.InitialPos)
+
+	var texts []string
+	for _, token := range tokens {
+		if token.Type == hclsyntax.TokenComment {
+			texts = append(texts, strings.TrimSpace(string(token.Bytes)))
+		}
+	}
+	sort.Strings(texts)
+	return texts
+}
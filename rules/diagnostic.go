@@ -0,0 +1,100 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Diagnostic is a problem a rule found in a file
+type Diagnostic struct {
+	File     string
+	Range    hcl.Range
+	Rule     string
+	Severity Severity
+	Message  string
+	Fix      *Fix // How to fix the problem, nil when it has to be fixed by hand
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", d.File, d.Range.Start.Line, d.Range.Start.Column, d.Severity, d.Message, d.Rule)
+}
+
+// Fix is a set of edits that resolves a diagnostic
+type Fix struct {
+	Edits []Edit
+}
+
+// Edit replaces the bytes of a range with new text
+type Edit struct {
+	Range   hcl.Range
+	NewText string
+}
+
+// Sort orders diagnostics by file and position, then by rule
+func Sort(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Range.Start.Byte != b.Range.Start.Byte {
+			return a.Range.Start.Byte < b.Range.Start.Byte
+		}
+		return a.Rule < b.Rule
+	})
+}
+
+// ApplyFixes applies the fixes of the diagnostics to content. A fix whose edits
+// overlap the edits of an earlier fix is skipped, so the returned count of
+// applied fixes can be lower than the number of fixes.
+func ApplyFixes(content []byte, diagnostics []Diagnostic) ([]byte, int, error) {
+	var edits []Edit
+	applied := 0
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Fix == nil {
+			continue
+		}
+		if !Fits(edits, diagnostic.Fix.Edits) {
+			continue
+		}
+		for _, edit := range diagnostic.Fix.Edits {
+			if edit.Range.Start.Byte < 0 || edit.Range.End.Byte > len(content) || edit.Range.Start.Byte > edit.Range.End.Byte {
+				return nil, 0, fmt.Errorf("%s: edit %d-%d is outside of the file", diagnostic.Rule, edit.Range.Start.Byte, edit.Range.End.Byte)
+			}
+		}
+		edits = append(edits, diagnostic.Fix.Edits...)
+		applied++
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Range.Start.Byte < edits[j].Range.Start.Byte
+	})
+
+	result := make([]byte, 0, len(content))
+	offset := 0
+	for _, edit := range edits {
+		result = append(result, content[offset:edit.Range.Start.Byte]...)
+		result = append(result, edit.NewText...)
+		offset = edit.Range.End.Byte
+	}
+	return append(result, content[offset:]...), applied, nil
+}
+
+// Fits reports whether none of the new edits overlap the existing ones, which
+// ApplyFixes requires of the fixes it applies together
+func Fits(existing, edits []Edit) bool {
+	for _, edit := range edits {
+		for _, other := range existing {
+			if edit.Range.Start.Byte < other.Range.End.Byte && other.Range.Start.Byte < edit.Range.End.Byte {
+				return false
+			}
+			// Two insertions at the same place have no defined order
+			if edit.Range.Start.Byte == other.Range.Start.Byte {
+				return false
+			}
+		}
+	}
+	return true
+}
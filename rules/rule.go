@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/vahid-haghighat/terralint/parser/types"
+)
+
+// Severity tells how serious a diagnostic is
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity returns the severity with the given name
+func ParseSeverity(name string) (Severity, error) {
+	for _, severity := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		if name == severity.String() {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q, expected info, warning or error", name)
+}
+
+// File is a parsed Terraform file handed to the rules
+type File struct {
+	Path    string        // The path used in diagnostics
+	Content []byte        // The source the AST was parsed from
+	Root    *types.Root   // The AST of the file, rules must not modify it
+	Module  *types.Module // The module of the file, nil when the file is checked on its own
+}
+
+// Rule checks a file for one kind of problem
+type Rule interface {
+	// ID is the stable name of the rule used in configuration and output
+	ID() string
+	// Severity is the severity of the diagnostics of the rule unless configured otherwise
+	Severity() Severity
+	// Description explains what the rule checks
+	Description() string
+	// Check returns the problems found in the file. The rule and severity of
+	// the diagnostics are filled in by Run.
+	Check(file *File) []Diagnostic
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Rule)
+)
+
+// Register makes a rule available under its ID. It panics if a rule with the
+// same ID is already registered.
+func Register(rule Rule) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, found := registry[rule.ID()]; found {
+		panic("rules: Register called twice for rule " + rule.ID())
+	}
+	registry[rule.ID()] = rule
+}
+
+// Lookup returns the rule registered under the ID
+func Lookup(id string) (Rule, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	rule, found := registry[id]
+	return rule, found
+}
+
+// All returns the registered rules sorted by ID
+func All() []Rule {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	all := make([]Rule, 0, len(registry))
+	for _, rule := range registry {
+		all = append(all, rule)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].ID() < all[j].ID()
+	})
+	return all
+}
+
+// Run checks the file with every rule and returns the diagnostics sorted by
+// their position in the file. The diagnostics silenced by the ignore
+// directives of the file are left out.
+func Run(file *File, rules []Rule) []Diagnostic {
+	ignored := suppressions(file.Root)
+
+	var diagnostics []Diagnostic
+	for _, rule := range rules {
+		for _, diagnostic := range rule.Check(file) {
+			diagnostic.Rule = rule.ID()
+			diagnostic.Severity = rule.Severity()
+			if diagnostic.File == "" {
+				diagnostic.File = file.Path
+			}
+			if suppressed(ignored, diagnostic) {
+				continue
+			}
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+
+	Sort(diagnostics)
+	return diagnostics
+}
+
+// WithSeverity returns the rule reporting its diagnostics with another severity
+func WithSeverity(rule Rule, severity Severity) Rule {
+	return severityRule{Rule: rule, severity: severity}
+}
+
+type severityRule struct {
+	Rule
+	severity Severity
+}
+
+func (r severityRule) Severity() Severity {
+	return r.severity
+}
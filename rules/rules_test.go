@@ -0,0 +1,161 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/vahid-haghighat/terralint/parser"
+	"github.com/vahid-haghighat/terralint/parser/types"
+)
+
+// testRule reports a fixed set of diagnostics
+type testRule struct {
+	id          string
+	diagnostics []Diagnostic
+}
+
+func (r testRule) ID() string {
+	return r.id
+}
+
+func (r testRule) Severity() Severity {
+	return SeverityWarning
+}
+
+func (r testRule) Description() string {
+	return "A rule used in tests"
+}
+
+func (r testRule) Check(file *File) []Diagnostic {
+	return r.diagnostics
+}
+
+// byteRange returns a range covering the bytes [start, end) of a single line file
+func byteRange(start, end int) hcl.Range {
+	return hcl.Range{
+		Filename: "main.tf",
+		Start:    hcl.Pos{Line: 1, Column: start + 1, Byte: start},
+		End:      hcl.Pos{Line: 1, Column: end + 1, Byte: end},
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(testRule{id: "test-register-b"})
+	Register(testRule{id: "test-register-a"})
+
+	if _, found := Lookup("test-register-a"); !found {
+		t.Errorf("Registered rule was not found")
+	}
+	if _, found := Lookup("test-register-missing"); found {
+		t.Errorf("Unregistered rule was found")
+	}
+
+	var ids []string
+	for _, rule := range All() {
+		if rule.ID() == "test-register-a" || rule.ID() == "test-register-b" {
+			ids = append(ids, rule.ID())
+		}
+	}
+	if expected := []string{"test-register-a", "test-register-b"}; !reflect.DeepEqual(expected, ids) {
+		t.Errorf("Rules are not sorted by ID: expected %v, got %v", expected, ids)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Registering a rule twice should panic")
+		}
+	}()
+	Register(testRule{id: "test-register-a"})
+}
+
+func TestRun(t *testing.T) {
+	file := &File{Path: "main.tf", Content: []byte("a = 1\n"), Root: &types.Root{}}
+	ruleSet := []Rule{
+		testRule{id: "second", diagnostics: []Diagnostic{{Range: byteRange(4, 5), Message: "late"}}},
+		testRule{id: "first", diagnostics: []Diagnostic{{Range: byteRange(0, 1), Message: "early"}}},
+	}
+
+	diagnostics := Run(file, ruleSet)
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d", len(diagnostics))
+	}
+
+	first := diagnostics[0]
+	if first.Message != "early" || first.Rule != "first" || first.File != "main.tf" || first.Severity != SeverityWarning {
+		t.Errorf("Diagnostic is not filled in or sorted: %+v", first)
+	}
+	if expected := "main.tf:1:1: warning: early (first)"; first.String() != expected {
+		t.Errorf("Diagnostic string mismatch: expected %q, got %q", expected, first.String())
+	}
+}
+
+// lineRange returns a range starting on the line, which sorts by rule only
+func lineRange(line int) hcl.Range {
+	return hcl.Range{
+		Filename: "main.tf",
+		Start:    hcl.Pos{Line: line, Column: 1},
+		End:      hcl.Pos{Line: line, Column: 2},
+	}
+}
+
+func TestRunHonorsIgnoreDirectives(t *testing.T) {
+	content := []byte(`# terralint-ignore-file everywhere
+
+resource "a" "b" {
+  # terralint-ignore first
+  x = 1
+  y = 2
+}
+`)
+	root, err := parser.ParseSource(content, "main.tf")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	file := &File{Path: "main.tf", Content: content, Root: root}
+
+	ruleSet := []Rule{
+		testRule{id: "first", diagnostics: []Diagnostic{
+			{Range: lineRange(5), Message: "ignored on x"},
+			{Range: lineRange(6), Message: "reported on y"},
+		}},
+		testRule{id: "second", diagnostics: []Diagnostic{{Range: lineRange(5), Message: "not ignored"}}},
+		testRule{id: "everywhere", diagnostics: []Diagnostic{{Range: lineRange(3), Message: "ignored in the file"}}},
+	}
+
+	var messages []string
+	for _, diagnostic := range Run(file, ruleSet) {
+		messages = append(messages, diagnostic.Message)
+	}
+	if expected := []string{"reported on y", "not ignored"}; !reflect.DeepEqual(expected, messages) {
+		t.Errorf("Reported diagnostics mismatch: expected %v, got %v", expected, messages)
+	}
+}
+
+func TestApplyFixes(t *testing.T) {
+	content := []byte("abcdef")
+	fix := func(start, end int, text string) Diagnostic {
+		return Diagnostic{Fix: &Fix{Edits: []Edit{{Range: byteRange(start, end), NewText: text}}}}
+	}
+
+	fixed, applied, err := ApplyFixes(content, []Diagnostic{
+		fix(4, 6, "EF"),
+		fix(0, 1, "A"),
+		{Message: "no fix"},
+		fix(0, 2, "overlapping"),
+		fix(3, 3, "-"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to apply fixes: %v", err)
+	}
+	if string(fixed) != "Abc-dEF" {
+		t.Errorf("Fixed content mismatch: expected %q, got %q", "Abc-dEF", fixed)
+	}
+	if applied != 3 {
+		t.Errorf("Expected 3 applied fixes, got %d", applied)
+	}
+
+	if _, _, err := ApplyFixes(content, []Diagnostic{fix(4, 10, "")}); err == nil {
+		t.Errorf("An edit outside of the file should fail")
+	}
+}
@@ -0,0 +1,104 @@
+package rules
+
+import (
+	"github.com/vahid-haghighat/terralint/parser/types"
+)
+
+// suppression silences rules on a range of lines
+type suppression struct {
+	rules     []string // The silenced rule IDs, all rules when empty
+	startLine int
+	endLine   int // Zero for the whole file
+}
+
+// suppressions returns what the ignore directives of the file silence. An
+// ignore directive covers the lines from itself to the end of the next item
+// that isn't a directive.
+func suppressions(root *types.Root) []suppression {
+	if root == nil {
+		return nil
+	}
+
+	var result []suppression
+	collect := func(children []types.Body) {
+		for i, child := range children {
+			directive, ok := child.(*types.FormatDirective)
+			if !ok {
+				continue
+			}
+
+			switch directive.DirectiveType {
+			case types.DirectiveIgnoreFile:
+				result = append(result, suppression{rules: directive.Parameters})
+			case types.DirectiveIgnore:
+				if item := nextItem(children[i+1:]); item != nil {
+					result = append(result, suppression{
+						rules:     directive.Parameters,
+						startLine: directive.Range.Start.Line,
+						endLine:   itemEndLine(item),
+					})
+				}
+			}
+		}
+	}
+
+	types.Inspect(root, func(node types.Node) bool {
+		switch n := node.(type) {
+		case *types.Root:
+			collect(n.Children)
+		case *types.Block:
+			collect(n.Children)
+		}
+		return true
+	})
+	return result
+}
+
+// nextItem returns the first of the items that isn't a directive
+func nextItem(items []types.Body) types.Body {
+	for _, item := range items {
+		if _, ok := item.(*types.FormatDirective); !ok {
+			return item
+		}
+	}
+	return nil
+}
+
+func itemEndLine(item types.Body) int {
+	switch i := item.(type) {
+	case *types.Block:
+		return i.Range.End.Line
+	case *types.Attribute:
+		return i.Range.End.Line
+	}
+	return 0
+}
+
+// silences reports whether the suppression covers the diagnostic
+func (s suppression) silences(diagnostic Diagnostic) bool {
+	if s.endLine > 0 {
+		line := diagnostic.Range.Start.Line
+		if line < s.startLine || line > s.endLine {
+			return false
+		}
+	}
+
+	if len(s.rules) == 0 {
+		return true
+	}
+	for _, id := range s.rules {
+		if id == diagnostic.Rule {
+			return true
+		}
+	}
+	return false
+}
+
+func suppressed(suppressions []suppression, diagnostic Diagnostic) bool {
+	for _, s := range suppressions {
+		if s.silences(diagnostic) {
+			return true
+		}
+	}
+	return false
+}